@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"os"
-	"strings"
+	"os/signal"
+	"syscall"
 
 	"github.com/ODIN7h3C0d3r/Netra/internal/cli"
+	"github.com/ODIN7h3C0d3r/Netra/internal/core"
 	"github.com/ODIN7h3C0d3r/Netra/internal/util"
 )
 
@@ -14,8 +17,7 @@ var (
 	date    = "unknown"
 )
 
-func init() {
-	util.PrintBanner(`
+const banner = `
 
 ███╗   ██╗███████╗████████╗██████╗  █████╗ 
 ████╗  ██║██╔════╝╚══██╔══╝██╔══██╗██╔══██╗
@@ -24,42 +26,50 @@ func init() {
 ██║ ╚████║███████╗   ██║   ██║  ██║██║  ██║
 ╚═╝  ╚═══╝╚══════╝   ╚═╝   ╚═╝  ╚═╝╚═╝  ╚═╝
                                            
-`)
-}
+`
 
 func main() {
+	if code, ok := cli.RunSubcommand(os.Args[1:]); ok {
+		os.Exit(code)
+	}
+
 	flags := cli.ParseFlags()
 	if flags.Help || flags.Version {
 		cli.Run(flags, []string{}, version)
 		return
 	}
 
-	if flags.Quiet {
-		util.SetQuiet(true)
+	// Load config before printing anything so quiet mode and colors apply to the banner too
+	cfg, err := cli.LoadConfig(flags)
+	if err != nil {
+		util.LogError("%v", err)
+		os.Exit(1)
 	}
 
-	// Only pass positional arguments (IPs) to the executor, not flags or their values
-	ips := []string{}
-	skipNext := false
-	for i := 1; i < len(os.Args); i++ {
-		arg := os.Args[i]
-		if skipNext {
-			skipNext = false
-			continue
-		}
-		if strings.HasPrefix(arg, "-") {
-			// If the flag expects a value, skip the next argument
-			if arg == "-output" || arg == "--output" || arg == "-file" || arg == "--file" || arg == "-format" || arg == "--format" || arg == "-fields" || arg == "--fields" {
-				skipNext = true
-			}
-			continue
-		}
-		if strings.Contains(arg, "=") {
-			continue
-		}
-		ips = append(ips, arg)
+	util.PrintBanner(banner)
+
+	// The first Ctrl-C stops new lookups and writes what we have; restoring the
+	// default handler then lets a second one kill the process immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if flags.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, flags.Deadline)
+		defer cancel()
 	}
 
-	executor := cli.NewCommandExecutor(flags, ips, version)
-	executor.Run()
+	executor := cli.NewCommandExecutor(flags, cfg, flags.Args, version)
+	code := executor.Run(ctx)
+
+	if err := core.Close(); err != nil {
+		util.LogWarning("Failed to close cache: %v", err)
+	}
+	if code != 0 {
+		os.Exit(code)
+	}
 }
@@ -0,0 +1,327 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/config"
+	"github.com/ODIN7h3C0d3r/Netra/internal/core"
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"github.com/ODIN7h3C0d3r/Netra/internal/util"
+)
+
+var cacheActions = []string{
+	"stats                                  Show entry count, size, age range and hit rate",
+	"list [-expired]                        List cached IPs",
+	"get <ip> [-format f] [-fields f]       Show the cached result for an IP",
+	"purge [-older-than 7d] [-cidr net] [-expired]",
+	"                                       Remove matching entries (all entries if no filter)",
+	"export [file]                          Write entries as JSON Lines (stdout by default)",
+	"import [file]                          Read entries exported by another machine (stdin by default)",
+}
+
+// runCacheCommand implements `netra cache <action>`
+func runCacheCommand(args []string) int {
+	if len(args) == 0 || args[0] == "-help" || args[0] == "--help" || args[0] == "-h" {
+		printSubcommandUsage("netra cache <command> [-config file] [OPTIONS]", cacheActions...)
+		return 2
+	}
+
+	action := args[0]
+	fs := flag.NewFlagSet("netra cache "+action, flag.ContinueOnError)
+	configFile := fs.String("config", "", "Path to config file")
+
+	var format, fields, olderThan, cidr *string
+	var expiredOnly *bool
+	switch action {
+	case "list":
+		expiredOnly = fs.Bool("expired", false, "Only list expired entries")
+	case "get":
+		format = fs.String("format", formatter.DefaultFormat, formatHelp())
+		fields = fs.String("fields", "", "Comma-separated fields to display")
+	case "purge":
+		olderThan = fs.String("older-than", "", "Remove entries stored longer ago than this (e.g. 12h, 7d, 2w)")
+		cidr = fs.String("cidr", "", "Remove entries inside this network (e.g. 10.0.0.0/8)")
+		expiredOnly = fs.Bool("expired", false, "Remove entries past the cache TTL")
+	case "stats", "export", "import":
+	default:
+		util.LogError("Unknown cache command: %s", action)
+		printSubcommandUsage("netra cache <command> [-config file] [OPTIONS]", cacheActions...)
+		return 2
+	}
+
+	rest, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		util.LogError("%v", err)
+		return 1
+	}
+	util.SetQuiet(cfg.UI.QuietMode)
+	util.SetColorTheme(cfg.UI.ColorTheme)
+	if err := registerTemplates(cfg); err != nil {
+		util.LogError("%v", err)
+		return 1
+	}
+
+	if !cfg.Cache.Enabled {
+		util.LogError("Persistent cache is disabled; set cache.enabled to true in the config file")
+		return 1
+	}
+
+	cache, err := core.OpenConfiguredCache(cfg)
+	if err != nil {
+		util.LogError("Failed to open cache: %v", err)
+		return 1
+	}
+	defer cache.Close()
+
+	switch action {
+	case "stats":
+		err = cacheStats(cache, cfg)
+	case "list":
+		err = cacheList(cache, *expiredOnly)
+	case "get":
+		if len(rest) != 1 {
+			util.LogError("Usage: netra cache get <ip>")
+			return 2
+		}
+		err = cacheGet(cache, rest[0], *format, *fields)
+	case "purge":
+		err = cachePurge(cache, *olderThan, *cidr, *expiredOnly)
+	case "export":
+		err = cacheExport(cache, optionalArg(rest))
+	case "import":
+		err = cacheImport(cache, optionalArg(rest))
+	}
+
+	if err != nil {
+		util.LogError("%v", err)
+		return 1
+	}
+	return 0
+}
+
+func optionalArg(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return ""
+}
+
+func cacheStats(cache *core.DiskCache, cfg *config.Config) error {
+	stats, err := cache.Stats()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "File:\t%s\n", cache.Path())
+	fmt.Fprintf(w, "Size:\t%s\n", util.HumanBytes(stats.SizeBytes))
+	fmt.Fprintf(w, "Entries:\t%d (%d expired)\n", stats.Entries, stats.Expired)
+	fmt.Fprintf(w, "TTL:\t%s\n", cfg.Cache.TTL.Duration)
+	fmt.Fprintf(w, "Hit rate:\t%.1f%% (%d hits, %d misses)\n", stats.HitRate()*100, stats.Hits, stats.Misses)
+	if stats.Entries > 0 {
+		fmt.Fprintf(w, "Oldest:\t%s (%s ago)\n", stats.Oldest.Local().Format(time.RFC3339), formatAge(stats.Oldest))
+		fmt.Fprintf(w, "Newest:\t%s (%s ago)\n", stats.Newest.Local().Format(time.RFC3339), formatAge(stats.Newest))
+	}
+	return w.Flush()
+}
+
+func cacheList(cache *core.DiskCache, expiredOnly bool) error {
+	items, err := cache.Items()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "IP\tAGE\tSTATUS\tCOUNTRY\tASN\tPROVIDER")
+	for _, item := range items {
+		if expiredOnly && !item.Expired {
+			continue
+		}
+		status := "fresh"
+		if item.Expired {
+			status = "expired"
+		}
+		var country, asn, provider string
+		if item.Info != nil {
+			country, asn, provider = item.Info.Country, item.Info.ASN, item.Info.Provider
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", item.IP, formatAge(item.Stored), status, country, asn, provider)
+	}
+	return w.Flush()
+}
+
+func cacheGet(cache *core.DiskCache, ip, format, fields string) error {
+	ip, err := util.CanonicalIP(ip, false)
+	if err != nil {
+		return err
+	}
+	if _, err := cache.Items(); err != nil {
+		return err
+	}
+
+	item, ok := cache.Item(ip)
+	if !ok || item.Info == nil {
+		return fmt.Errorf("%s is not cached", ip)
+	}
+
+	output, err := formatter.Format([]*formatter.IPInfo{item.Info}, format, fields)
+	if err != nil {
+		return err
+	}
+	fmt.Println(output)
+
+	// Keep machine-readable output clean; LogInfo writes to stdout
+	if format != "text" && format != "" {
+		return nil
+	}
+	status := "fresh"
+	if item.Expired {
+		status = "expired"
+	}
+	util.LogInfo("Stored %s (%s ago, %s)", item.Stored.Local().Format(time.RFC3339), formatAge(item.Stored), status)
+	return nil
+}
+
+func cachePurge(cache *core.DiskCache, olderThan, cidr string, expiredOnly bool) error {
+	var maxAge time.Duration
+	if olderThan != "" {
+		d, err := util.ParseDuration(olderThan)
+		if err != nil {
+			return fmt.Errorf("invalid -older-than: %v", err)
+		}
+		maxAge = d
+	}
+
+	var network *net.IPNet
+	if cidr != "" {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid -cidr: %v", err)
+		}
+		network = n
+	}
+
+	now := time.Now()
+	removed, err := cache.Purge(func(item core.CacheItem) bool {
+		if olderThan != "" && now.Sub(item.Stored) <= maxAge {
+			return false
+		}
+		if network != nil && !network.Contains(net.ParseIP(item.IP)) {
+			return false
+		}
+		if expiredOnly && !item.Expired {
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	util.LogInfo("Purged %d entries", removed)
+	return nil
+}
+
+func cacheExport(cache *core.DiskCache, path string) error {
+	items, err := cache.Items()
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if path != "" && path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %v", path, err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	w := bufio.NewWriter(out)
+	enc := json.NewEncoder(w)
+	for _, item := range items {
+		if err := enc.Encode(item); err != nil {
+			return fmt.Errorf("failed to write export: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write export: %v", err)
+	}
+
+	if path != "" && path != "-" {
+		util.LogInfo("Exported %d entries to %s", len(items), path)
+	}
+	return nil
+}
+
+func cacheImport(cache *core.DiskCache, path string) error {
+	var in io.Reader = os.Stdin
+	if path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %v", path, err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var items []core.CacheItem
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var item core.CacheItem
+		if err := json.Unmarshal([]byte(text), &item); err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+		ip, err := util.CanonicalIP(item.IP, false)
+		if err != nil || item.Info == nil {
+			util.LogWarning("Skipping line %d: missing IP or info", line)
+			continue
+		}
+		item.IP = ip
+		items = append(items, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read import: %v", err)
+	}
+
+	if err := cache.Import(items); err != nil {
+		return err
+	}
+	util.LogInfo("Imported %d entries", len(items))
+	return nil
+}
+
+// formatAge renders the time since t at a human scale, e.g. "3h", "2d"
+func formatAge(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
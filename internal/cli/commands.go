@@ -1,137 +1,679 @@
 package cli
 
 import (
-	"flag"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"sync"
+	"sync/atomic"
 
+	"github.com/ODIN7h3C0d3r/Netra/internal/config"
 	"github.com/ODIN7h3C0d3r/Netra/internal/core"
 	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"github.com/ODIN7h3C0d3r/Netra/internal/input"
+	"github.com/ODIN7h3C0d3r/Netra/internal/network"
 	"github.com/ODIN7h3C0d3r/Netra/internal/util"
 )
 
+// Exit codes for runs that stop early
+const (
+	ExitDeadline    = 124 // -deadline elapsed, as with timeout(1)
+	ExitInterrupted = 130 // SIGINT or SIGTERM, as shells report 128+SIGINT
+)
+
 // CommandExecutor handles execution flow based on flags
 type CommandExecutor struct {
 	flags   *Flags
+	config  *config.Config
 	args    []string
 	version string
+
+	// sawHost is set when an entry needs DNS resolution; like the other
+	// columns it is settled by scanInput before the first result is written
+	sawHost bool
+
+	// sawTransition is set when an entry is, or may expand or resolve to,
+	// an IPv6 transition address
+	sawTransition bool
+
+	// sawOriginal is set when an address entry is not in canonical form
+	sawOriginal bool
+
+	// piped is set when the entries could not be scanned up front; formats
+	// without fixed columns then take the optional ones from first, the
+	// first result written
+	piped bool
+	first *formatter.IPInfo
+
+	// tooLarge records the first entry skipped for exceeding -max-expand, so
+	// a run with nothing else to look up can say why
+	tooLarge atomic.Pointer[input.TooLargeError]
 }
 
 // NewCommandExecutor creates a new executor
-func NewCommandExecutor(flags *Flags, args []string, version string) *CommandExecutor {
+func NewCommandExecutor(flags *Flags, cfg *config.Config, args []string, version string) *CommandExecutor {
+	if cfg == nil {
+		cfg = config.Default()
+	}
 	return &CommandExecutor{
 		flags:   flags,
+		config:  cfg,
 		args:    args,
 		version: version,
 	}
 }
 
-// Run executes the appropriate command
-func (c *CommandExecutor) Run() {
+// Run executes the appropriate command and returns the process exit code.
+// When ctx ends (Ctrl-C or -deadline) no new lookups are started and the
+// results gathered so far are still written.
+func (c *CommandExecutor) Run(ctx context.Context) int {
 	if c.flags.Version {
 		fmt.Fprintf(os.Stdout, "Netra v%s\n", c.version)
-		return
+		return 0
 	}
 
 	if c.flags.Help {
-		flag.Usage()
-		return
+		printUsage()
+		return 0
 	}
 
 	if c.flags.Interactive {
-		runInteractiveMode(c.flags.Format, c.flags.Fields)
-		return
+		runInteractiveMode(ctx, c.flags.Format, c.flags.Fields)
+		return exitCode(ctx)
 	}
 
-	// Get IPs from args or file
-	ips := c.getIPs()
+	if err := c.checkInputFormat(); err != nil {
+		util.LogError("%v", err)
+		return 1
+	}
 
-	if len(ips) == 0 && !c.flags.Interactive {
+	// Entries are streamed from args, -file or stdin
+	in, err := c.openInput()
+	if err != nil {
+		util.LogError("Failed to read file: %v", err)
+		return 1
+	}
+	if in == nil && len(c.args) == 0 {
 		fmt.Fprintln(os.Stderr, "Error: No IP addresses provided")
-		flag.Usage()
-		os.Exit(1)
+		printUsage()
+		return 1
+	}
+	if in != nil {
+		defer in.Close()
+	}
+	if err := c.scanInput(ctx, in); err != nil {
+		util.LogError("Failed to read input: %v", err)
+		return 1
 	}
 
-	results := processIPsConcurrently(ips)
-
-	// Format and output results
-	formatted, err := formatter.Format(results, c.flags.Format, c.flags.Fields)
+	out, err := newResultWriter(c.flags, c.outputFields)
 	if err != nil {
 		util.LogError("Formatting failed: %v", err)
-		os.Exit(1)
+		return 1
 	}
+	// Error paths only need the output finished; the final Close below
+	// reports its error
+	defer out.Close()
 
-	if c.flags.OutputFile != "" {
-		fmt.Fprintf(os.Stdout, "[DEBUG] Output file path: %s\n", c.flags.OutputFile)
-		err := util.SaveToFile(c.flags.OutputFile, formatted)
-		if err != nil {
-			util.LogError("Failed to save output: %v", err)
-			os.Exit(1)
+	// A failed write stops the remaining lookups; the caller still gets to
+	// close the cache
+	lookupCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var writeErr error
+	var writeMu sync.Mutex
+
+	// Results are written as they arrive rather than after the whole batch
+	readErr := make(chan error, 1)
+	entries := c.entries(lookupCtx, in, readErr)
+	inputs := c.lookup(lookupCtx, c.expand(lookupCtx, entries), func(info *formatter.IPInfo, lookupErr error) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if writeErr != nil {
+			return
+		}
+		if c.first == nil {
+			c.first = info
+		}
+		write := out.Write
+		if lookupErr != nil {
+			write = func(info *formatter.IPInfo) error { return out.WriteError(info, lookupErr) }
 		}
+		if err := write(info); err != nil {
+			writeErr = err
+			cancel()
+		}
+	})
+
+	if writeErr != nil {
+		util.LogError("Failed to write output: %v", writeErr)
+		return 1
+	}
+
+	select {
+	case err := <-readErr:
+		util.LogError("Failed to read input: %v", err)
+		return 1
+	default:
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		util.LogWarning("%s: wrote %d results for %d inputs", stopReason(ctx), out.Count(), inputs)
+	case inputs == 0 && c.tooLarge.Load() != nil:
+		// Reported even with -quiet, which hides the warning above
+		fmt.Fprintf(os.Stderr, "Error: %v; raise -max-expand or use -sample\n", c.tooLarge.Load())
+		return 1
+	case inputs == 0 && c.flags.Extract:
+		util.LogError("No IP addresses found in the input")
+		return 1
+	case inputs == 0:
+		fmt.Fprintln(os.Stderr, "Error: No IP addresses provided")
+		printUsage()
+		return 1
+	}
+
+	if err := out.Close(); err != nil {
+		util.LogError("Failed to save output: %v", err)
+		return 1
+	}
+	if c.flags.OutputFile != "" && out.Records() > 0 {
 		fmt.Fprintf(os.Stdout, "Output saved to %s\n", c.flags.OutputFile)
-	} else {
-		fmt.Println(formatted)
 	}
+	if ctx.Err() == nil && out.Count() == 0 {
+		util.LogError("All lookups failed")
+		return 1
+	}
+	return exitCode(ctx)
 }
 
 // Add a public Run function for main.go compatibility
 func Run(flags *Flags, args []string, version string) {
-	executor := NewCommandExecutor(flags, args, version)
-	executor.Run()
+	executor := NewCommandExecutor(flags, nil, args, version)
+	executor.Run(context.Background())
 }
 
-// getIPs returns IPs from args or file
-func (c *CommandExecutor) getIPs() []string {
-	if c.flags.InputFile != "" {
-		ips, err := util.ReadLines(c.flags.InputFile)
-		if err != nil {
-			util.LogError("Failed to read file: %v", err)
-			os.Exit(1)
+// exitCode maps how the run context ended to the process exit code
+func exitCode(ctx context.Context) int {
+	switch ctx.Err() {
+	case nil:
+		return 0
+	case context.DeadlineExceeded:
+		return ExitDeadline
+	}
+	return ExitInterrupted
+}
+
+// stopReason describes why the run context ended
+func stopReason(ctx context.Context) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "Deadline exceeded"
+	}
+	return "Interrupted"
+}
+
+// outputFields returns the -fields selection, or when none was given the
+// default columns plus count (with -unique), resolved_from (when a
+// hostname, URL or email address is among the entries) and the transition
+// columns (when an entry may be an IPv6 transition address) and original
+// (when an address entry is not in canonical form), as found by
+// scanInput, so every row has the same columns. Unscanned piped input
+// takes them from the first result instead.
+func (c *CommandExecutor) outputFields() string {
+	if c.flags.Fields != "" {
+		return c.flags.Fields
+	}
+
+	host, transition, original := c.sawHost, c.sawTransition, c.sawOriginal
+	if c.piped && c.first != nil {
+		host = host || c.first.ResolvedFrom != ""
+		transition = transition || c.first.Transition != ""
+		original = original || c.first.Original != ""
+	}
+
+	var extra []string
+	if original {
+		extra = append(extra, "+original")
+	}
+	if c.flags.Unique {
+		extra = append(extra, "+count")
+	}
+	if host {
+		extra = append(extra, "+resolved_from")
+	}
+	if transition {
+		extra = append(extra, "+transition", "+embedded_ipv4")
+		if c.config.API.LookupEmbedded {
+			extra = append(extra, "+embedded_country", "+embedded_asn")
 		}
-		return filterValidIPs(ips)
 	}
+	if c.flags.Extract {
+		extra = append(extra, "+line")
+	}
+	if c.structuredInput() {
+		extra = append(extra, "+input")
+	}
+	return strings.Join(extra, ",")
+}
 
-	return filterValidIPs(c.args)
+// structuredInput reports whether entries are CSV or JSON rows whose
+// columns are passed through to the output
+func (c *CommandExecutor) structuredInput() bool {
+	return c.flags.InputFormat == "csv" || c.flags.InputFormat == "jsonl"
 }
 
-// processIPsConcurrently processes multiple IPs in parallel
-func processIPsConcurrently(ips []string) []*formatter.IPInfo {
-	var wg sync.WaitGroup
-	results := make([]*formatter.IPInfo, len(ips))
-	errs := make([]error, len(ips))
+// checkInputFormat validates -input-format against the other input options
+func (c *CommandExecutor) checkInputFormat() error {
+	switch c.flags.InputFormat {
+	case "", "lines":
+		if c.flags.IPColumn != "" {
+			return fmt.Errorf("-ip-column needs -input-format csv or jsonl")
+		}
+		return nil
+	case "csv", "jsonl":
+	default:
+		return fmt.Errorf("unknown -input-format %q (available: %s)", c.flags.InputFormat, strings.Join(input.InputFormats, ", "))
+	}
 
-	for i, ip := range ips {
-		wg.Add(1)
-		go func(i int, ip string) {
-			defer wg.Done()
-			info, err := core.GetIPInfo(ip)
-			results[i] = info
-			errs[i] = err
-		}(i, ip)
+	if c.flags.Extract {
+		return fmt.Errorf("-extract reads free text and cannot be combined with -input-format %s", c.flags.InputFormat)
+	}
+	if c.flags.InputFile == "" && len(c.args) > 0 {
+		return fmt.Errorf("-input-format %s reads -file or stdin, not arguments", c.flags.InputFormat)
+	}
+	return nil
+}
+
+// scanInput reads every entry before any lookup so the optional columns are
+// known before the first result is written; a column that only appeared
+// with a later entry would otherwise be missing from the rows. A regular
+// file is read twice. Piped input cannot be, so formats with fixed columns
+// (csv, table) always get the columns its entries could need.
+func (c *CommandExecutor) scanInput(ctx context.Context, in io.ReadCloser) error {
+	if c.flags.Fields != "" {
+		return nil
+	}
+	f, _ := in.(*os.File)
+	if in != nil {
+		if st, err := f.Stat(); f == nil || err != nil || !st.Mode().IsRegular() {
+			c.piped = true
+			if format, ok := formatter.Lookup(c.flags.Format); ok && format.FixedColumns() {
+				c.sawHost, c.sawTransition, c.sawOriginal = true, true, true
+			}
+			return nil
+		}
+	}
+
+	errc := make(chan error, 1)
+	for entry := range c.entries(ctx, in, errc) {
+		c.scanEntry(entry)
+	}
+	select {
+	case err := <-errc:
+		return err
+	default:
+	}
+	if f != nil {
+		_, err := f.Seek(0, io.SeekStart)
+		return err
+	}
+	return nil
+}
+
+// scanEntry records the optional columns an entry's results will fill
+func (c *CommandExecutor) scanEntry(entry input.Entry) {
+	text := strings.TrimSpace(entry.Text)
+	if host, ok := input.Hostname(text); ok {
+		c.sawHost = true
+		if ip, err := util.CanonicalIP(host, c.flags.Unmap); err == nil {
+			c.scanAddress(ip)
+		} else {
+			// A hostname can resolve to a transition address, e.g. the
+			// NAT64 addresses a DNS64 resolver answers with
+			c.sawTransition = true
+		}
+		return
 	}
 
-	wg.Wait()
+	if ip, err := util.CanonicalIP(text, c.flags.Unmap); err == nil {
+		if ip != text {
+			c.sawOriginal = true
+		}
+		c.scanAddress(ip)
+	} else if input.IsRange(text) {
+		if r, err := input.ParseRange(text); err == nil {
+			for _, p := range util.TransitionPrefixes {
+				if r.Overlaps(p) {
+					c.sawTransition = true
+				}
+			}
+		}
+	}
+}
+
+// scanAddress records the optional columns a single address will fill
+func (c *CommandExecutor) scanAddress(ip string) {
+	if _, ok := util.DecodeTransition(ip); ok {
+		c.sawTransition = true
+	}
+}
+
+// openInput opens the stream to read entries from: the -file path, stdin
+// for "-file -", or stdin when there are no positional arguments and it is
+// not a terminal. It returns nil when the entries are the arguments (with
+// -extract, the arguments are the text to scan).
+func (c *CommandExecutor) openInput() (io.ReadCloser, error) {
+	switch {
+	case c.flags.InputFile == "-":
+		return os.Stdin, nil
+	case c.flags.InputFile != "":
+		return os.Open(c.flags.InputFile)
+	case len(c.args) == 0 && !isTerminal(os.Stdin):
+		return os.Stdin, nil
+	}
+	return nil, nil
+}
+
+// isTerminal reports whether f is an interactive terminal rather than a pipe or file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
 
-	// Report errors
-	for i, err := range errs {
+// entries streams the raw input entries (IPs, CIDR blocks, ranges, hosts)
+// from the arguments, or line by line from in with comments removed. With
+// -extract the input is scanned as free text instead and every address found
+// becomes an entry, deduplicated unless -unique is counting them. A read
+// error stops the stream and is sent on errc.
+func (c *CommandExecutor) entries(ctx context.Context, in io.Reader, errc chan<- error) <-chan input.Entry {
+	out := make(chan input.Entry)
+	send := func(e input.Entry) bool {
+		select {
+		case out <- e:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	go func() {
+		defer close(out)
+		if in == nil && c.flags.Extract {
+			in = strings.NewReader(strings.Join(c.args, "\n"))
+		}
+
+		var err error
+		switch {
+		case in == nil:
+			for _, arg := range c.args {
+				if entry := input.StripComment(arg); entry != "" && !send(input.Entry{Text: entry}) {
+					return
+				}
+			}
+		case c.flags.InputFormat == "csv":
+			err = input.ReadCSV(in, c.flags.IPColumn, send)
+		case c.flags.InputFormat == "jsonl":
+			err = input.ReadJSONL(in, c.flags.IPColumn, send)
+		case c.flags.Extract:
+			err = input.Extract(in, !c.flags.Unique, func(ip string, line int) bool {
+				return send(input.Entry{Text: ip, Line: line})
+			})
+		default:
+			err = input.ReadEntries(in, func(entry string) bool {
+				return send(input.Entry{Text: entry})
+			})
+		}
 		if err != nil {
-			util.LogWarning("Failed to fetch info for %s: %v", ips[i], err)
+			errc <- err
+		}
+	}()
+	return out
+}
+
+// expand streams the addresses of every entry, expanding CIDR blocks and
+// ranges lazily so even a /8 is never held in memory. Hostnames, URLs and
+// email addresses are resolved to all of their A and AAAA records. Invalid
+// entries and ones larger than -max-expand are skipped with a warning.
+func (c *CommandExecutor) expand(ctx context.Context, entries <-chan input.Entry) <-chan input.Target {
+	opts := input.Options{MaxExpand: c.flags.MaxExpand, Sample: c.flags.Sample, Unmap: c.flags.Unmap}
+	resolver := network.NewDNSResolver(c.config.Network.DNSServers)
+	targets := make(chan input.Target)
+
+	send := func(t input.Target) bool {
+		select {
+		case targets <- t:
+			return true
+		case <-ctx.Done():
+			return false
 		}
 	}
 
-	return results
+	go func() {
+		defer close(targets)
+		for entry := range entries {
+			if entry.Text == "" {
+				util.LogWarning("Skipping row with an empty IP column")
+				continue
+			}
+			if host, ok := input.Hostname(entry.Text); ok {
+				c.resolve(ctx, resolver, host, entry, send)
+			} else {
+				text := strings.TrimSpace(entry.Text)
+				err := input.Expand(text, opts, func(ip string) bool {
+					t := input.Target{IP: ip, Line: entry.Line, Columns: entry.Columns}
+					if ip != text && !input.IsRange(text) {
+						t.Original = text
+					}
+					return send(t)
+				})
+
+				var tooLarge *input.TooLargeError
+				switch {
+				case errors.As(err, &tooLarge):
+					c.tooLarge.CompareAndSwap(nil, tooLarge)
+					util.LogWarning("Skipping %s: expands to %s addresses (limit %d); raise -max-expand or use -sample", entry.Text, tooLarge.Size, tooLarge.Limit)
+				case err != nil:
+					util.LogWarning("Skipping invalid IP: %s", entry.Text)
+				}
+			}
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+
+	return targets
 }
 
-// filterValidIPs removes invalid IPs from list
-func filterValidIPs(ips []string) []string {
-	var valid []string
+// resolve sends every distinct address host resolves to, tagged with the
+// entry it came from
+func (c *CommandExecutor) resolve(ctx context.Context, resolver *network.DNSResolver, host string, entry input.Entry, send func(input.Target) bool) {
+	ips := []string{host}
+	if ip, err := util.CanonicalIP(host, c.flags.Unmap); err == nil {
+		ips = []string{ip}
+	} else {
+		var err error
+		if ips, err = resolver.GetAllIPs(ctx, host); err != nil {
+			if ctx.Err() == nil {
+				util.LogWarning("Failed to resolve %s: %v", entry.Text, err)
+			}
+			return
+		}
+		util.LogStatus("Resolved %s to %s", host, strings.Join(ips, ", "))
+	}
+
+	seen := make(map[string]bool)
 	for _, ip := range ips {
-		if util.IsValidIP(ip) {
-			valid = append(valid, ip)
-		} else {
-			util.LogWarning("Skipping invalid IP: %s", ip)
+		if seen[ip] {
+			continue
+		}
+		seen[ip] = true
+		if !send(input.Target{IP: ip, Source: entry.Text, Line: entry.Line, Columns: entry.Columns}) {
+			return
 		}
 	}
-	return valid
+}
+
+// lookup resolves every input address and passes each result to write,
+// keeping one row per input address or, with -unique, one row per distinct
+// IP with the number of times it appeared. Addresses resolved from a
+// hostname carry it in resolved_from. A failed lookup is passed as a row
+// holding only the address and its input columns, with the error. With
+// -ordered results are released in input order; otherwise as soon as they
+// complete. It returns the number of addresses read from targets.
+func (c *CommandExecutor) lookup(ctx context.Context, targets <-chan input.Target, write func(info *formatter.IPInfo, err error)) int {
+	var counts map[string]int
+	total := 0
+	if c.flags.Unique {
+		var unique []input.Target
+		unique, counts = countUnique(targets)
+		for _, n := range counts {
+			total += n
+		}
+		targets = feed(ctx, unique)
+	}
+
+	emit := func(i int, t input.Target, info *formatter.IPInfo, err error) {
+		if info == nil {
+			if err == nil {
+				return
+			}
+			info = &formatter.IPInfo{IP: t.IP}
+		}
+		if counts != nil || t.Source != "" || t.Original != "" || t.Line > 0 || t.Columns != nil {
+			// Copy so per-input columns never leak into the cached result
+			row := *info
+			row.Count = counts[t.IP]
+			row.ResolvedFrom = t.Source
+			row.Original = t.Original
+			row.Line = t.Line
+			row.Input = t.Columns
+			info = &row
+		}
+		write(info, err)
+	}
+
+	var n int
+	if c.flags.Ordered {
+		buf := newReorderBuffer(emit)
+		n = processIPsConcurrently(ctx, targets, c.config.API.Concurrency, buf.Add)
+		buf.Flush()
+	} else {
+		n = processIPsConcurrently(ctx, targets, c.config.API.Concurrency, emit)
+	}
+
+	if counts != nil {
+		return total
+	}
+	return n
+}
+
+// feed streams a slice of targets until it is exhausted or ctx is done
+func feed(ctx context.Context, targets []input.Target) <-chan input.Target {
+	out := make(chan input.Target)
+	go func() {
+		defer close(out)
+		for _, t := range targets {
+			select {
+			case out <- t:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// countUnique drains targets and returns the distinct IPs in first-seen
+// order (keeping the first hostname each was resolved from) and how often
+// each occurs
+func countUnique(targets <-chan input.Target) ([]input.Target, map[string]int) {
+	var unique []input.Target
+	counts := make(map[string]int)
+	for t := range targets {
+		if counts[t.IP] == 0 {
+			unique = append(unique, t)
+		}
+		counts[t.IP]++
+	}
+	return unique, counts
+}
+
+// DefaultConcurrency is the number of lookup workers when api.concurrency is unset
+const DefaultConcurrency = 10
+
+// lookupResult is the outcome of one lookup, identified by its input index
+type lookupResult struct {
+	index  int
+	target input.Target
+	info   *formatter.IPInfo
+	err    error
+}
+
+// lookupJob is one target to look up and its position in the input
+type lookupJob struct {
+	index  int
+	target input.Target
+}
+
+// processIPsConcurrently looks up addresses with a bounded pool of workers and
+// calls emit from the calling goroutine as each lookup completes, with a nil
+// info and the error where it failed (a nil error if it was cancelled).
+// Request pacing is left to the providers' rate limiters and duplicate IPs
+// share one request inside core.GetIPInfo. Once ctx is done no further IPs
+// are handed out and lookups in flight are aborted. It returns the number of
+// targets taken from targets.
+func processIPsConcurrently(ctx context.Context, targets <-chan input.Target, workers int, emit func(i int, t input.Target, info *formatter.IPInfo, err error)) int {
+	if workers <= 0 {
+		workers = DefaultConcurrency
+	}
+
+	jobs := make(chan lookupJob)
+	results := make(chan lookupResult)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				info, err := core.GetIPInfo(ctx, job.target.IP)
+				results <- lookupResult{index: job.index, target: job.target, info: info, err: err}
+			}
+		}()
+	}
+
+	dispatched := 0
+	go func() {
+	dispatch:
+		for t := range targets {
+			select {
+			case jobs <- lookupJob{index: dispatched, target: t}:
+				dispatched++
+			case <-ctx.Done():
+				break dispatch
+			}
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	for r := range results {
+		if r.err != nil {
+			if ctx.Err() != nil && errors.Is(r.err, ctx.Err()) {
+				// Lookups aborted by cancellation are summarized by the caller
+				r.err = nil
+			} else {
+				util.LogWarning("Failed to fetch info for %s: %v", r.target.IP, r.err)
+			}
+			r.info = nil
+		}
+		emit(r.index, r.target, r.info, r.err)
+	}
+
+	// results is closed only after the dispatcher finished counting
+	return dispatched
 }
@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/config"
+	"github.com/ODIN7h3C0d3r/Netra/internal/core"
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"github.com/ODIN7h3C0d3r/Netra/internal/network"
+	"github.com/ODIN7h3C0d3r/Netra/internal/util"
+)
+
+// LoadConfig loads the config file, merges it with the flags (flags win)
+// and applies the result to the logger and the lookup pipeline
+func LoadConfig(flags *Flags) (*config.Config, error) {
+	cfg, err := config.Load(flags.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := registerTemplates(cfg); err != nil {
+		return nil, err
+	}
+	if flags.TemplateFile != "" {
+		if flags.Template != "" {
+			return nil, fmt.Errorf("-template and -template-file cannot be combined")
+		}
+		data, err := os.ReadFile(flags.TemplateFile)
+		if err != nil {
+			return nil, fmt.Errorf("-template-file: %v", err)
+		}
+		flags.Template = string(data)
+	}
+
+	if !flags.IsSet("format") {
+		// -template implies the template format; an -output extension
+		// (results.csv) beats the configured default
+		if flags.Template != "" {
+			flags.Format = "template"
+		} else if f, ok := formatter.ForPath(flags.OutputFile); ok {
+			flags.Format = f.Name()
+		} else if cfg.Format.Default != "" {
+			flags.Format = cfg.Format.Default
+		}
+	}
+	format, ok := formatter.Lookup(flags.Format)
+	if !ok {
+		return nil, fmt.Errorf("-format: %v", formatter.UnsupportedFormat(flags.Format))
+	}
+	if format.Name() == "template" {
+		if flags.Template == "" {
+			return nil, fmt.Errorf("-format template needs -template or -template-file")
+		}
+		if _, err := formatter.ParseTemplate(flags.Template); err != nil {
+			return nil, fmt.Errorf("-template: %v", err)
+		}
+	} else if flags.Template != "" {
+		util.LogWarning("-template is ignored by the %s format", format.Name())
+	}
+	if flags.Sort != "" {
+		if format.Streaming() {
+			return nil, fmt.Errorf("-sort needs a format that is written at the end, such as -format table")
+		}
+		key := strings.TrimPrefix(flags.Sort, "-")
+		structured := flags.InputFormat == "csv" || flags.InputFormat == "jsonl"
+		if !formatter.IsField(key) && !structured {
+			return nil, fmt.Errorf("-sort: unknown field %q", key)
+		}
+	}
+	if flags.GroupBy != "" {
+		if format.Name() != "kml" {
+			return nil, fmt.Errorf("-group-by needs -format kml")
+		}
+		flags.GroupBy = strings.ToLower(flags.GroupBy)
+		if flags.GroupBy != "country" && flags.GroupBy != "asn" {
+			return nil, fmt.Errorf("-group-by: unknown grouping %q (available: %s)", flags.GroupBy, strings.Join(formatter.KMLGroups, ", "))
+		}
+	}
+	if flags.Append {
+		if flags.OutputFile == "" {
+			return nil, fmt.Errorf("-append needs -output")
+		}
+		if !format.Appendable() {
+			return nil, fmt.Errorf("-append: %s output cannot be appended to; use jsonl", format.Name())
+		}
+	}
+	if !flags.IsSet("fields") && cfg.Format.Fields != "" {
+		flags.Fields = cfg.Format.Fields
+	}
+	if flags.Provider != "" {
+		applyProviders(cfg, flags.Provider)
+	}
+	if flags.IsSet("consensus") {
+		cfg.Providers.Consensus = flags.Consensus
+	}
+	if flags.Databases != "" {
+		applyDatabases(cfg, flags)
+	}
+	if flags.IsSet("concurrency") {
+		if flags.Concurrency < 1 {
+			return nil, fmt.Errorf("-concurrency must be at least 1 (got %d)", flags.Concurrency)
+		}
+		cfg.API.Concurrency = flags.Concurrency
+	}
+	if flags.IsSet("rate") {
+		if _, err := network.ParseRate(flags.Rate); err != nil {
+			return nil, fmt.Errorf("-rate: %v", err)
+		}
+		cfg.API.RateLimit = flags.Rate
+	}
+	if flags.IsSet("lookup-embedded") {
+		cfg.API.LookupEmbedded = flags.Embedded
+	}
+	if !flags.IsSet("quiet") {
+		flags.Quiet = cfg.UI.QuietMode
+	}
+
+	if cfg.Providers.Consensus && len(cfg.Providers.Chain) < 2 {
+		return nil, fmt.Errorf("-consensus needs at least two providers (e.g. -provider ipapi,ipinfo,ip-api)")
+	}
+
+	util.SetQuiet(flags.Quiet)
+	util.SetColorTheme(cfg.UI.ColorTheme)
+
+	if flags.Fields != "" && !format.SelectsFields() {
+		util.LogWarning("-fields is ignored by the %s format", format.Name())
+	}
+
+	if err := core.Configure(cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.Path != "" {
+		util.LogStatus("Loaded config from %s", cfg.Path)
+	}
+	return cfg, nil
+}
+
+// registerTemplates makes the config's named templates available as formats
+func registerTemplates(cfg *config.Config) error {
+	for name, text := range cfg.Format.Templates {
+		if err := formatter.RegisterTemplate(name, text); err != nil {
+			return fmt.Errorf("format.templates: %v", err)
+		}
+	}
+	return nil
+}
+
+// applyProviders replaces the configured provider selection with the -provider
+// value; a comma-separated list becomes a fallback chain
+func applyProviders(cfg *config.Config, value string) {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+
+	cfg.Providers.Default = names[0]
+	cfg.Providers.Chain = nil
+	if len(names) > 1 {
+		cfg.Providers.Chain = names
+	}
+}
+
+// applyDatabases points the selected (or the default "mmdb") provider at the -db files
+func applyDatabases(cfg *config.Config, flags *Flags) {
+	name := "mmdb"
+	if flags.Provider != "" {
+		name = cfg.Providers.Default
+	}
+
+	if cfg.Providers.Backends == nil {
+		cfg.Providers.Backends = make(map[string]config.ProviderConfig)
+	}
+	backend := cfg.Providers.Backends[name]
+	if backend.Type == "" {
+		backend.Type = "mmdb"
+	}
+	backend.Databases = nil
+	for _, path := range strings.Split(flags.Databases, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			backend.Databases = append(backend.Databases, util.ExpandHome(path))
+		}
+	}
+
+	cfg.Providers.Backends[name] = backend
+	cfg.Providers.Default = name
+}
@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/config"
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"github.com/ODIN7h3C0d3r/Netra/internal/input"
+	"github.com/ODIN7h3C0d3r/Netra/internal/util"
+)
+
+// addLookupFlags registers the output and provider options shared by the
+// subcommands that collect addresses and enrich them (logs, pcap). -format
+// is left to the subcommand, so the output format is -output-format.
+func addLookupFlags(fs *flag.FlagSet, flags *Flags) {
+	fs.StringVar(&flags.Format, "output-format", formatter.DefaultFormat, formatHelp())
+	fs.StringVar(&flags.Fields, "fields", "", "Comma-separated fields to display (prefix with + to add to the defaults)")
+	fs.StringVar(&flags.OutputFile, "output", "", "Save output to file")
+	fs.BoolVar(&flags.Append, "append", false, appendHelp())
+	fs.StringVar(&flags.Sort, "sort", "", "Sort table rows by a field, e.g. -bytes for descending (-output-format table)")
+	fs.BoolVar(&flags.ASCII, "ascii", false, "Draw table borders with plain ASCII")
+	fs.StringVar(&flags.GroupBy, "group-by", "", "Group KML placemarks into folders by country or asn (-output-format kml)")
+	fs.StringVar(&flags.Template, "template", "", "Go text/template for each result (-output-format template)")
+	fs.StringVar(&flags.TemplateFile, "template-file", "", "Read the -template from a file")
+	fs.StringVar(&flags.ConfigFile, "config", "", "Path to config file")
+	fs.StringVar(&flags.Provider, "provider", "", "Geolocation provider or comma-separated fallback chain")
+	fs.IntVar(&flags.Concurrency, "concurrency", DefaultConcurrency, "Maximum number of lookups running in parallel")
+	fs.BoolVar(&flags.Embedded, "lookup-embedded", false, "Also look up the IPv4 address embedded in IPv6 transition addresses")
+	fs.BoolVar(&flags.Quiet, "quiet", false, "Suppress progress output")
+}
+
+// markSetFlags records which flags of fs were given so LoadConfig lets
+// them override the config file
+func markSetFlags(fs *flag.FlagSet, flags *Flags) {
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "output-format":
+			flags.set["format"] = true
+		case "format":
+			// The subcommand's own -format (e.g. the log format), not the
+			// output format
+		default:
+			flags.set[f.Name] = true
+		}
+	})
+}
+
+// enrichAndWrite looks up every address once and writes one row per
+// successful lookup in the order given, with apply filling in the
+// subcommand's own columns for address i. It returns the exit code.
+func enrichAndWrite(ctx context.Context, cfg *config.Config, flags *Flags, ips []string, fields func() string, apply func(i int, row *formatter.IPInfo)) int {
+	out, err := newResultWriter(flags, fields)
+	if err != nil {
+		util.LogError("Formatting failed: %v", err)
+		return 1
+	}
+	defer out.Close()
+
+	targets := make([]input.Target, len(ips))
+	for i, ip := range ips {
+		targets[i] = input.Target{IP: ip}
+	}
+
+	var writeErr error
+	buf := newReorderBuffer(func(i int, t input.Target, info *formatter.IPInfo, err error) {
+		if writeErr != nil || (info == nil && err == nil) {
+			return
+		}
+		if info == nil {
+			row := formatter.IPInfo{IP: t.IP}
+			apply(i, &row)
+			writeErr = out.WriteError(&row, err)
+			return
+		}
+		// Copy so the subcommand's columns never reach the cache
+		row := *info
+		apply(i, &row)
+		writeErr = out.Write(&row)
+	})
+	processIPsConcurrently(ctx, feed(ctx, targets), cfg.API.Concurrency, buf.Add)
+	buf.Flush()
+
+	if writeErr != nil {
+		util.LogError("Failed to write output: %v", writeErr)
+		return 1
+	}
+	if ctx.Err() != nil {
+		util.LogWarning("%s: wrote %d of %d addresses", stopReason(ctx), out.Count(), len(ips))
+	}
+
+	if err := out.Close(); err != nil {
+		util.LogError("Failed to save output: %v", err)
+		return 1
+	}
+	if flags.OutputFile != "" && out.Records() > 0 {
+		fmt.Fprintf(os.Stdout, "Output saved to %s\n", flags.OutputFile)
+	}
+	if ctx.Err() == nil && out.Count() == 0 {
+		util.LogError("All lookups failed")
+		return 1
+	}
+	return exitCode(ctx)
+}
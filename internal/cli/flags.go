@@ -4,27 +4,81 @@ import (
     "flag"
     "fmt"
     "os"
+    "time"
+
+    "github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+    "github.com/ODIN7h3C0d3r/Netra/internal/input"
+    "github.com/ODIN7h3C0d3r/Netra/internal/util"
 )
 
 // Flags holds all parsed command-line options
 type Flags struct {
-    Format      string
-    InputFile   string
-    OutputFile  string
-    Quiet       bool
-    Interactive bool
-    Help        bool
-    Version     bool
-    Fields      string
+    Format       string
+    InputFile    string
+    OutputFile   string
+    Append       bool
+    Sort         string
+    ASCII        bool
+    GroupBy      string
+    Template     string
+    TemplateFile string
+    ConfigFile   string
+    Provider     string
+    Databases    string
+    Consensus    bool
+    Concurrency  int
+    Rate         string
+    Unique       bool
+    Ordered      bool
+    MaxExpand    uint64
+    Sample       bool
+    Extract      bool
+    InputFormat  string
+    IPColumn     string
+    Embedded     bool
+    Unmap        bool
+    Deadline     time.Duration
+    Quiet        bool
+    Interactive  bool
+    Help         bool
+    Version      bool
+    Fields       string
+
+    // Args holds the positional arguments (IPs), which may be interleaved with flags
+    Args []string
+
+    set map[string]bool
 }
 
 // ParseFlags processes command-line arguments and returns Flags struct
 func ParseFlags() *Flags {
-    flags := &Flags{}
+    flags := &Flags{set: make(map[string]bool)}
 
-    flag.StringVar(&flags.Format, "format", "text", "Output format: text/json/csv/yaml")
-    flag.StringVar(&flags.InputFile, "file", "", "Path to file containing IPs (one per line)")
+    flag.StringVar(&flags.Format, "format", formatter.DefaultFormat, formatHelp())
+    flag.StringVar(&flags.InputFile, "file", "", "Path to file containing IPs or hosts (one per line, # comments allowed); - reads stdin, which is also used when no arguments are given")
     flag.StringVar(&flags.OutputFile, "output", "", "Save output to file")
+    flag.BoolVar(&flags.Append, "append", false, appendHelp())
+    flag.StringVar(&flags.Sort, "sort", "", "Sort table or KML rows by a field, e.g. country or -count for descending (-format table, kml)")
+    flag.BoolVar(&flags.ASCII, "ascii", false, "Draw table borders with plain ASCII instead of Unicode box characters")
+    flag.StringVar(&flags.GroupBy, "group-by", "", "Group KML placemarks into folders by country or asn (-format kml)")
+    flag.StringVar(&flags.Template, "template", "", "Go text/template for each result, e.g. '{{.IP}} {{.Country}} ({{.ASN}})'; implies -format template")
+    flag.StringVar(&flags.TemplateFile, "template-file", "", "Read the -template from a file")
+    flag.StringVar(&flags.ConfigFile, "config", "", "Path to config file (default $XDG_CONFIG_HOME/netra/config.json, then ./config/config.json)")
+    flag.StringVar(&flags.Provider, "provider", "", "Geolocation provider: ipapi/ipinfo/ip-api or a name from the config's providers section; a comma-separated list is tried in order")
+    flag.BoolVar(&flags.Consensus, "consensus", false, "Query all -provider entries in parallel and merge fields by majority vote")
+    flag.StringVar(&flags.Databases, "db", "", "Comma-separated MaxMind .mmdb files (e.g. GeoLite2-City.mmdb,GeoLite2-ASN.mmdb) for offline lookups")
+    flag.IntVar(&flags.Concurrency, "concurrency", 10, "Maximum number of lookups running in parallel")
+    flag.StringVar(&flags.Rate, "rate", "", "Request quota per provider, e.g. 45/min or 1000/day (default: each provider's free-tier limit)")
+    flag.BoolVar(&flags.Unique, "unique", false, "Output one row per distinct IP with a count column instead of one per input line")
+    flag.Uint64Var(&flags.MaxExpand, "max-expand", input.DefaultMaxExpand, "Largest number of addresses a single CIDR or range may expand to (0 = no limit)")
+    flag.BoolVar(&flags.Sample, "sample", false, "Look up one address per /24 (IPv4) or /48 (IPv6) of each CIDR or range")
+    flag.BoolVar(&flags.Extract, "extract", false, "Find IPs anywhere in the input text (logs, reports, defanged IOCs like 8.8.8[.]8) instead of reading one entry per line")
+    flag.StringVar(&flags.InputFormat, "input-format", "lines", "Input format: lines (one entry per line), csv (with a header row) or jsonl; csv and jsonl columns are kept in the output")
+    flag.StringVar(&flags.IPColumn, "ip-column", "", "CSV column (name or number) or JSON path (e.g. $.source.ip) holding the address; common names like ip and src_ip are tried by default")
+    flag.BoolVar(&flags.Embedded, "lookup-embedded", false, "Also look up the IPv4 address embedded in IPv4-mapped, 6to4, Teredo and NAT64 addresses")
+    flag.BoolVar(&flags.Unmap, "unmap", false, "Treat IPv4-mapped IPv6 addresses (::ffff:a.b.c.d) as plain IPv4")
+    flag.BoolVar(&flags.Ordered, "ordered", false, "Write results in input order instead of as soon as each lookup finishes")
+    flag.Var((*durationValue)(&flags.Deadline), "deadline", "Stop the whole run after this long and write partial results (e.g. 5m, 1h)")
     flag.BoolVar(&flags.Quiet, "quiet", false, "Suppress progress output")
     flag.BoolVar(&flags.Interactive, "interactive", false, "Enter interactive mode")
     flag.BoolVar(&flags.Help, "help", false, "Show help message")
@@ -32,12 +86,68 @@ func ParseFlags() *Flags {
     flag.StringVar(&flags.Fields, "fields", "", "Comma-separated fields to display (e.g. ip,country,isp)")
 
     flag.Usage = func() {
-        fmt.Fprintf(os.Stderr, "Usage: netra [OPTIONS] [IP1 IP2 ...]\n\n")
-        flag.PrintDefaults()
+        printUsage()
         os.Exit(0)
     }
 
-    flag.Parse()
+    flags.Args, _ = parseInterspersed(flag.CommandLine, os.Args[1:])
+
+    flag.Visit(func(f *flag.Flag) {
+        flags.set[f.Name] = true
+    })
 
     return flags
-}
\ No newline at end of file
+}
+
+// printUsage writes the usage text to stderr
+func printUsage() {
+    fmt.Fprintf(os.Stderr, "Usage: netra [OPTIONS] [IP | CIDR | START-END | HOST | URL ...]\n")
+    fmt.Fprintf(os.Stderr, "       netra <command> [OPTIONS]   (commands: %s)\n\n", subcommandNames())
+    flag.PrintDefaults()
+    printFormats()
+}
+
+// IsSet reports whether a flag was given explicitly on the command line
+func (f *Flags) IsSet(name string) bool {
+    return f.set[name]
+}
+
+// parseInterspersed parses a flag set while allowing flags after positional
+// arguments ("netra 8.8.8.8 -format json"); flag.Parse alone stops at the
+// first positional. It returns the positional arguments in order.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+    var positional []string
+    for {
+        if err := fs.Parse(args); err != nil {
+            return nil, err
+        }
+        rest := fs.Args()
+        if len(rest) == 0 {
+            return positional, nil
+        }
+        if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+            return append(positional, rest...), nil
+        }
+        positional = append(positional, rest[0])
+        args = rest[1:]
+    }
+}
+
+// durationValue is a flag.Value accepting util.ParseDuration syntax ("90s", "5m", "1d")
+type durationValue time.Duration
+
+func (d *durationValue) String() string {
+    return time.Duration(*d).String()
+}
+
+func (d *durationValue) Set(s string) error {
+    v, err := util.ParseDuration(s)
+    if err != nil {
+        return err
+    }
+    if v <= 0 {
+        return fmt.Errorf("must be greater than zero")
+    }
+    *d = durationValue(v)
+    return nil
+}
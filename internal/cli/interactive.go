@@ -2,6 +2,7 @@ package cli
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -11,18 +12,39 @@ import (
 	"github.com/ODIN7h3C0d3r/Netra/internal/util"
 )
 
-// runInteractiveMode starts the REPL loop
-func runInteractiveMode(format, fields string) {
-	scanner := bufio.NewScanner(os.Stdin)
+// runInteractiveMode starts the REPL loop; it returns on "exit", end of
+// input or when ctx is done
+func runInteractiveMode(ctx context.Context, format, fields string) {
 	history := make(map[string]bool)
 
+	// Read stdin in the background so a signal can end the loop while it
+	// is waiting for input
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
 	fmt.Println("🚀 Netra Interactive Mode")
 	fmt.Println("Enter IP address (type 'exit' to quit, 'history' to view lookups)")
 
 	for {
 		fmt.Print("> ")
-		scanner.Scan()
-		input := strings.TrimSpace(scanner.Text())
+		var input string
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				fmt.Println()
+				return
+			}
+			input = strings.TrimSpace(line)
+		case <-ctx.Done():
+			fmt.Println()
+			return
+		}
 
 		if input == "exit" {
 			break
@@ -43,7 +65,11 @@ func runInteractiveMode(format, fields string) {
 
 		history[input] = true
 
-		info, err := core.GetIPInfo(input)
+		info, err := core.GetIPInfo(ctx, input)
+		if ctx.Err() != nil {
+			fmt.Println()
+			return
+		}
 		if err != nil {
 			util.LogError("Failed to fetch info: %v", err)
 			continue
@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/core"
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"github.com/ODIN7h3C0d3r/Netra/internal/logs"
+	"github.com/ODIN7h3C0d3r/Netra/internal/util"
+)
+
+const logsUsage = "netra logs [-format nginx-combined|apache|sshd|jsonl] [OPTIONS] [FILE ...]"
+
+// runLogsCommand implements `netra logs`: it parses web server, sshd or
+// JSON-lines logs, looks up every client address once and writes one row
+// per address with its request count, first/last seen time and most
+// requested paths or most tried users, busiest client first
+func runLogsCommand(args []string) int {
+	flags := &Flags{set: make(map[string]bool)}
+	fs := flag.NewFlagSet("netra logs", flag.ContinueOnError)
+	logFormat := fs.String("format", "nginx-combined", "Log format: "+strings.Join(logs.Formats, "/"))
+	jsonPath := fs.String("json-path", "", "JSONPath of the client address in jsonl logs (e.g. $.request.remote_ip); common field names are tried by default")
+	top := fs.Int("top", 3, "Number of top paths/users to show per address")
+	addLookupFlags(fs, flags)
+	fs.Usage = func() {
+		printSubcommandUsage(logsUsage)
+		fmt.Fprintf(os.Stderr, "\nReads standard input when no file is given.\n\n")
+		fs.PrintDefaults()
+	}
+
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	markSetFlags(fs, flags)
+
+	parser, err := logs.NewParser(*logFormat, logs.Options{JSONPath: *jsonPath})
+	if err != nil {
+		util.LogError("%v", err)
+		return 2
+	}
+	if len(files) == 0 {
+		if isTerminal(os.Stdin) {
+			fs.Usage()
+			return 2
+		}
+		files = []string{"-"}
+	}
+
+	cfg, err := LoadConfig(flags)
+	if err != nil {
+		util.LogError("%v", err)
+		return 1
+	}
+	defer func() {
+		if err := core.Close(); err != nil {
+			util.LogWarning("Failed to save cache: %v", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	agg := logs.NewAggregator()
+	for _, path := range files {
+		if err := readLog(ctx, path, parser, agg); err != nil {
+			util.LogError("%v", err)
+			return 1
+		}
+	}
+	if agg.Len() == 0 {
+		util.LogError("No client addresses found; check -format")
+		return 1
+	}
+	results := agg.Results()
+	util.LogInfo("Looking up %d distinct addresses", len(results))
+
+	ips := make([]string, len(results))
+	for i, r := range results {
+		ips[i] = r.IP
+	}
+	fields := func() string { return logFields(flags.Fields, results) }
+	return enrichAndWrite(ctx, cfg, flags, ips, fields, func(i int, row *formatter.IPInfo) {
+		applyAggregate(row, results[i], *top)
+	})
+}
+
+// readLog feeds every record of one log file ("-" for stdin) to agg
+func readLog(ctx context.Context, path string, parser logs.Parser, agg *logs.Aggregator) error {
+	var in io.Reader = os.Stdin
+	name := "stdin"
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %v", path, err)
+		}
+		defer f.Close()
+		in = f
+		name = path
+	}
+
+	parsed, skipped := 0, 0
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() && ctx.Err() == nil {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		rec, ok := parser.Parse(line)
+		if !ok {
+			skipped++
+			continue
+		}
+		agg.Add(rec)
+		parsed++
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %v", name, err)
+	}
+
+	util.LogInfo("Parsed %d records from %s", parsed, name)
+	if skipped > 0 {
+		util.LogWarning("Skipped %d unrecognized lines in %s", skipped, name)
+	}
+	return nil
+}
+
+// logFields returns the -fields selection or the default columns plus the
+// aggregates the parsed log actually carries
+func logFields(fields string, results []*logs.Aggregate) string {
+	if fields != "" {
+		return fields
+	}
+
+	extra := []string{"+count", "+first_seen", "+last_seen"}
+	var paths, users bool
+	for _, r := range results {
+		paths = paths || len(r.Paths) > 0
+		users = users || len(r.Users) > 0
+	}
+	if paths {
+		extra = append(extra, "+top_paths")
+	}
+	if users {
+		extra = append(extra, "+top_users")
+	}
+	return strings.Join(extra, ",")
+}
+
+// applyAggregate copies a client's log statistics onto its lookup result
+func applyAggregate(row *formatter.IPInfo, agg *logs.Aggregate, top int) {
+	row.Count = agg.Requests
+	if !agg.FirstSeen.IsZero() {
+		row.FirstSeen = agg.FirstSeen.Format(time.RFC3339)
+		row.LastSeen = agg.LastSeen.Format(time.RFC3339)
+	}
+	row.TopPaths = util.TopCounts(agg.Paths, top)
+	row.TopUsers = util.TopCounts(agg.Users, top)
+}
@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"github.com/ODIN7h3C0d3r/Netra/internal/input"
+	"github.com/ODIN7h3C0d3r/Netra/internal/util"
+)
+
+// formatHelp is the help text of the output format flags
+func formatHelp() string {
+	return "Output format: " + strings.Join(formatter.Names(), "/")
+}
+
+// appendHelp is the -append usage, naming the formats that can be appended to
+func appendHelp() string {
+	var names []string
+	for _, f := range formatter.Formats() {
+		if f.Appendable() {
+			names = append(names, f.Name())
+		}
+	}
+	return "Append to the -output file instead of replacing it (" + strings.Join(names, ", ") + ")"
+}
+
+// printFormats lists the registered output formats in usage output, with
+// the columns as wide as their longest entry
+func printFormats() {
+	formats := formatter.Formats()
+	nameWidth, mimeWidth := 0, 0
+	for _, f := range formats {
+		if len(f.Name()) > nameWidth {
+			nameWidth = len(f.Name())
+		}
+		if len(f.MIMEType()) > mimeWidth {
+			mimeWidth = len(f.MIMEType())
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "\nOutput formats (-format; also picked from the -output file extension):\n")
+	for _, f := range formats {
+		if len(f.Extensions()) == 0 {
+			fmt.Fprintf(os.Stderr, "  %-*s %s\n", nameWidth, f.Name(), f.MIMEType())
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "  %-*s %-*s .%s\n", nameWidth, f.Name(), mimeWidth, f.MIMEType(), strings.Join(f.Extensions(), ", ."))
+	}
+}
+
+// resultWriter streams results to stdout or the -output file. The file is
+// only created, and the format's header only written, when the first result
+// arrives, so a run in which every lookup fails leaves an existing file alone
+// unless the format records failures (jsonl).
+type resultWriter struct {
+	path   string
+	format string
+	append bool
+	opts   formatter.Options
+	fields func() string
+
+	// errors is set when the format records failed lookups
+	errors bool
+
+	file   *os.File
+	stream formatter.StreamWriter
+	count  int
+	failed int
+}
+
+// newResultWriter checks the format and fields up front so a typo is
+// reported before any lookup is made. fields is asked again for the final
+// selection when the first result arrives. Output to a terminal is fitted
+// to its width and colored.
+func newResultWriter(flags *Flags, fields func() string) (*resultWriter, error) {
+	opts := formatter.Options{Sort: flags.Sort, ASCII: flags.ASCII, Template: flags.Template, GroupBy: flags.GroupBy}
+	if flags.OutputFile == "" && isTerminal(os.Stdout) {
+		opts.Width = util.TerminalWidth(os.Stdout)
+		opts.Color = util.ColorsEnabled()
+	}
+
+	opts.Fields = fields()
+	probe, err := formatter.NewStreamWriterWith(io.Discard, flags.Format, opts)
+	if err != nil {
+		return nil, err
+	}
+	_, errors := probe.(formatter.ErrorWriter)
+	return &resultWriter{
+		path:   flags.OutputFile,
+		format: flags.Format,
+		append: flags.Append,
+		opts:   opts,
+		fields: fields,
+		errors: errors,
+	}, nil
+}
+
+func (w *resultWriter) open() error {
+	var dst io.Writer = os.Stdout
+	if w.path != "" {
+		create := util.CreateFile
+		if w.append {
+			create = util.AppendFile
+		}
+		f, err := create(w.path)
+		if err != nil {
+			return err
+		}
+		w.file = f
+		dst = f
+	}
+
+	opts := w.opts
+	opts.Fields = w.fields()
+	stream, err := formatter.NewStreamWriterWith(dst, w.format, opts)
+	if err != nil {
+		return err
+	}
+	w.stream = stream
+	return stream.Begin()
+}
+
+// Write emits one result
+func (w *resultWriter) Write(info *formatter.IPInfo) error {
+	if w.stream == nil {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+	w.count++
+	return w.stream.Write(info)
+}
+
+// WriteError records a failed lookup in formats that support it; others
+// leave it to the warning already logged
+func (w *resultWriter) WriteError(info *formatter.IPInfo, lookupErr error) error {
+	if !w.errors {
+		return nil
+	}
+	if w.stream == nil {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+	w.failed++
+	return w.stream.(formatter.ErrorWriter).WriteError(info, lookupErr)
+}
+
+// Count returns the number of results written so far
+func (w *resultWriter) Count() int {
+	return w.count
+}
+
+// Records returns the number of results and recorded failures written so far
+func (w *resultWriter) Records() int {
+	return w.count + w.failed
+}
+
+// Close finishes the output; it does nothing if nothing was written or the
+// output is already closed
+func (w *resultWriter) Close() error {
+	if w.stream == nil {
+		return nil
+	}
+	err := w.stream.End()
+	if w.file != nil {
+		if cerr := w.file.Close(); err == nil {
+			err = cerr
+		}
+	}
+	w.stream, w.file = nil, nil
+	return err
+}
+
+// pendingResult is a result held back by reorderBuffer
+type pendingResult struct {
+	target input.Target
+	info   *formatter.IPInfo
+	err    error
+}
+
+// reorderBuffer releases results in input order, holding back those that
+// complete before an earlier one
+type reorderBuffer struct {
+	next    int
+	pending map[int]pendingResult
+	emit    func(i int, t input.Target, info *formatter.IPInfo, err error)
+}
+
+func newReorderBuffer(emit func(i int, t input.Target, info *formatter.IPInfo, err error)) *reorderBuffer {
+	return &reorderBuffer{pending: make(map[int]pendingResult), emit: emit}
+}
+
+// Add records the result for input i (nil and the error if it failed) and
+// releases every result that is now in sequence
+func (b *reorderBuffer) Add(i int, t input.Target, info *formatter.IPInfo, err error) {
+	b.pending[i] = pendingResult{target: t, info: info, err: err}
+	for {
+		r, ok := b.pending[b.next]
+		if !ok {
+			return
+		}
+		delete(b.pending, b.next)
+		b.emit(b.next, r.target, r.info, r.err)
+		b.next++
+	}
+}
+
+// Flush releases the results still held back, in input order, after a run
+// stopped early and left gaps
+func (b *reorderBuffer) Flush() {
+	for len(b.pending) > 0 {
+		if r, ok := b.pending[b.next]; ok {
+			delete(b.pending, b.next)
+			b.emit(b.next, r.target, r.info, r.err)
+		}
+		b.next++
+	}
+}
@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/core"
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"github.com/ODIN7h3C0d3r/Netra/internal/iana"
+	"github.com/ODIN7h3C0d3r/Netra/internal/pcap"
+	"github.com/ODIN7h3C0d3r/Netra/internal/util"
+)
+
+const pcapUsage = "netra pcap [-include-private] [OPTIONS] FILE.pcap|FILE.pcapng ..."
+
+// runPcapCommand implements `netra pcap`: it reads packet captures, collects
+// every source and destination address with its packet and byte counts and
+// writes one enriched row per address, most traffic first
+func runPcapCommand(args []string) int {
+	flags := &Flags{set: make(map[string]bool)}
+	fs := flag.NewFlagSet("netra pcap", flag.ContinueOnError)
+	includePrivate := fs.Bool("include-private", false, "Also report private, loopback, link-local, multicast and other non-global addresses (never looked up)")
+	top := fs.Int("top", 3, "Number of top service ports to show per address")
+	addLookupFlags(fs, flags)
+	fs.Usage = func() {
+		printSubcommandUsage(pcapUsage)
+		fmt.Fprintf(os.Stderr, "\nReads standard input when the file is -.\n\n")
+		fs.PrintDefaults()
+	}
+
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(files) == 0 {
+		fs.Usage()
+		return 2
+	}
+	markSetFlags(fs, flags)
+
+	cfg, err := LoadConfig(flags)
+	if err != nil {
+		util.LogError("%v", err)
+		return 1
+	}
+	defer func() {
+		if err := core.Close(); err != nil {
+			util.LogWarning("Failed to save cache: %v", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	keep := isPublicAddr
+	if *includePrivate {
+		keep = nil
+	}
+	endpoints := pcap.NewEndpoints(keep)
+	for _, path := range files {
+		if err := readCapture(ctx, path, endpoints); err != nil {
+			util.LogError("%v", err)
+			return 1
+		}
+	}
+	if endpoints.Len() == 0 {
+		util.LogError("No public addresses found in the capture; use -include-private to list local ones")
+		return 1
+	}
+
+	results := endpoints.Results()
+	util.LogInfo("Looking up %d distinct addresses", len(results))
+
+	ips := make([]string, len(results))
+	for i, ep := range results {
+		ips[i] = ep.Addr.String()
+	}
+	fields := func() string {
+		if flags.Fields != "" {
+			return flags.Fields
+		}
+		return "+packets,+bytes,+first_seen,+last_seen,+top_ports"
+	}
+	return enrichAndWrite(ctx, cfg, flags, ips, fields, func(i int, row *formatter.IPInfo) {
+		ep := results[i]
+		row.Packets = ep.Packets
+		row.Bytes = ep.Bytes
+		if !ep.FirstSeen.IsZero() {
+			row.FirstSeen = ep.FirstSeen.Format(time.RFC3339)
+			row.LastSeen = ep.LastSeen.Format(time.RFC3339)
+		}
+		row.TopPorts = util.TopCounts(ep.Ports, *top)
+	})
+}
+
+// readCapture adds every decodable packet of one capture ("-" for stdin)
+// to endpoints
+func readCapture(ctx context.Context, path string, endpoints *pcap.Endpoints) error {
+	var in io.Reader = os.Stdin
+	name := "stdin"
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %v", path, err)
+		}
+		defer f.Close()
+		in = f
+		name = path
+	}
+
+	rd, err := pcap.NewReader(in)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+
+	packets, skipped := 0, 0
+	var bytes int64
+	for ctx.Err() == nil {
+		p, err := rd.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Captures cut off mid-write are common; keep what was read
+			util.LogWarning("%s: stopped after %d packets: %v", name, packets+skipped, err)
+			break
+		}
+
+		flow, ok := pcap.Decode(p.LinkType, p.Data)
+		if !ok {
+			skipped++
+			continue
+		}
+		endpoints.Add(p, flow)
+		packets++
+		bytes += int64(p.Length)
+	}
+
+	util.LogInfo("Read %d IP packets (%s) from %s", packets, util.HumanBytes(bytes), name)
+	if skipped > 0 {
+		util.LogInfo("Skipped %d non-IP frames in %s", skipped, name)
+	}
+	return nil
+}
+
+// isPublicAddr reports whether an address is worth looking up: globally
+// reachable according to the IANA special-purpose registries
+func isPublicAddr(addr netip.Addr) bool {
+	return iana.Classify(addr).Global
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// subcommands maps the first command-line argument to a command that parses
+// its own flags and returns the process exit code
+var subcommands = map[string]func(args []string) int{
+	"cache": runCacheCommand,
+	"logs":  runLogsCommand,
+	"pcap":  runPcapCommand,
+}
+
+// RunSubcommand runs the subcommand named by args[0]. ok is false when
+// args does not start with a subcommand and normal IP lookup should run.
+func RunSubcommand(args []string) (code int, ok bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+	run, found := subcommands[args[0]]
+	if !found {
+		return 0, false
+	}
+	return run(args[1:]), true
+}
+
+// subcommandNames lists the available subcommands for help output
+func subcommandNames() string {
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// printSubcommandUsage writes the usage line shared by subcommand help
+func printSubcommandUsage(usage string, actions ...string) {
+	fmt.Fprintf(os.Stderr, "Usage: %s\n", usage)
+	if len(actions) > 0 {
+		fmt.Fprintf(os.Stderr, "\nCommands:\n")
+		for _, a := range actions {
+			fmt.Fprintf(os.Stderr, "  %s\n", a)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"github.com/ODIN7h3C0d3r/Netra/internal/network"
+	"github.com/ODIN7h3C0d3r/Netra/internal/util"
+)
+
+// DefaultFileName is the name netra looks for inside each config directory
+const DefaultFileName = "config.json"
+
+// Config mirrors the layout of config/config.json
+type Config struct {
+	API       APIConfig       `json:"api"`
+	Providers ProvidersConfig `json:"providers"`
+	Cache     CacheConfig     `json:"cache"`
+	Format    FormatConfig    `json:"format"`
+	Network   NetworkConfig   `json:"network"`
+	UI        UIConfig        `json:"ui"`
+
+	// Path is the file the configuration was loaded from (empty for built-in defaults)
+	Path string `json:"-"`
+}
+
+// APIConfig controls how the geolocation API is queried
+type APIConfig struct {
+	BaseURL    string   `json:"base_url"`
+	Token      string   `json:"token"`
+	RetryLimit int      `json:"retry_limit"`
+	Timeout    Duration `json:"timeout"`
+
+	// Concurrency bounds parallel lookups in batch mode
+	Concurrency int `json:"concurrency"`
+
+	// RateLimit ("45/min") overrides the request quota of every provider;
+	// empty uses each provider's rate_limit or published free-tier quota
+	RateLimit string `json:"rate_limit"`
+
+	// LookupEmbedded also looks up the IPv4 address embedded in IPv6
+	// transition addresses (IPv4-mapped, 6to4, Teredo, NAT64)
+	LookupEmbedded bool `json:"lookup_embedded"`
+}
+
+// ProvidersConfig selects the geolocation provider and holds per-provider settings.
+// api.base_url and api.token still configure the default "ipapi" provider.
+type ProvidersConfig struct {
+	Default  string                    `json:"default"`
+	Backends map[string]ProviderConfig `json:"backends"`
+
+	// Chain lists providers tried in order when the previous one fails.
+	// With Consensus set they are all queried and merged by majority vote.
+	Chain     []string `json:"chain"`
+	Consensus bool     `json:"consensus"`
+}
+
+// ProviderConfig configures one named provider. Type defaults to the entry's
+// name, so {"ipinfo": {"token": "..."}} configures the built-in ipinfo provider.
+type ProviderConfig struct {
+	Type    string `json:"type"`
+	BaseURL string `json:"base_url"`
+	Token   string `json:"token"`
+
+	// URL and Fields describe a "template" provider: URL may contain {ip} and
+	// {token}; Fields maps output field names to dotted JSON paths
+	URL    string            `json:"url"`
+	Fields map[string]string `json:"fields"`
+
+	// Databases lists MaxMind .mmdb files for the offline "mmdb" provider
+	Databases []string `json:"databases"`
+
+	// RateLimit ("45/min", "1000/day") overrides the provider's default quota
+	RateLimit string `json:"rate_limit"`
+}
+
+// CacheConfig controls result caching
+type CacheConfig struct {
+	Enabled bool     `json:"enabled"`
+	TTL     Duration `json:"ttl"`
+
+	// Path overrides the cache file location ($XDG_CACHE_HOME/netra/cache.jsonl)
+	Path string `json:"path"`
+}
+
+// FormatConfig holds output defaults used when no flag overrides them
+type FormatConfig struct {
+	Default string `json:"default"`
+	Fields  string `json:"fields"`
+
+	// Templates are named output templates, each usable as a format of its
+	// own (-format ticket)
+	Templates map[string]string `json:"templates"`
+}
+
+// NetworkConfig holds proxy and DNS settings
+type NetworkConfig struct {
+	Proxy      string   `json:"proxy"`
+	DNSServers []string `json:"dns_servers"`
+}
+
+// UIConfig controls terminal output
+type UIConfig struct {
+	ColorTheme string `json:"color_theme"`
+	QuietMode  bool   `json:"quiet_mode"`
+}
+
+// Duration is a time.Duration that is written as a string ("10s", "24h", "7d") in JSON
+type Duration struct {
+	time.Duration
+	raw string
+}
+
+// UnmarshalJSON stores the raw value; it is parsed during validation so errors can name the key
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"10s\" or \"24h\"")
+	}
+	d.raw = s
+	return nil
+}
+
+// MarshalJSON writes the duration back in its string form
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) parse(key string) error {
+	if d.raw == "" {
+		return nil
+	}
+	v, err := util.ParseDuration(d.raw)
+	if err != nil {
+		return fmt.Errorf("%s: invalid duration %q (use values like \"30s\", \"24h\" or \"7d\")", key, d.raw)
+	}
+	d.Duration = v
+	return nil
+}
+
+// Default returns the built-in configuration used when no file is found
+func Default() *Config {
+	return &Config{
+		API: APIConfig{
+			BaseURL:     "https://ipapi.co",
+			RetryLimit:  3,
+			Timeout:     Duration{Duration: 10 * time.Second},
+			Concurrency: 10,
+		},
+		Providers: ProvidersConfig{
+			Default: "ipapi",
+		},
+		Cache: CacheConfig{
+			Enabled: true,
+			TTL:     Duration{Duration: 24 * time.Hour},
+		},
+		Format: FormatConfig{
+			Default: "text",
+		},
+		UI: UIConfig{
+			ColorTheme: "dark",
+		},
+	}
+}
+
+// SearchPaths returns the locations checked for a config file, in order
+func SearchPaths(explicit string) []string {
+	if explicit != "" {
+		return []string{util.ExpandHome(explicit)}
+	}
+
+	var paths []string
+	if dir := userConfigDir(); dir != "" {
+		paths = append(paths, filepath.Join(dir, "netra", DefaultFileName))
+	}
+	paths = append(paths, filepath.Join("config", DefaultFileName))
+	return paths
+}
+
+// Load reads the first config file found in the search order
+// (explicit path, $XDG_CONFIG_HOME/netra/config.json, ./config/config.json).
+// An explicit path must exist; otherwise missing files fall back to defaults.
+func Load(explicit string) (*Config, error) {
+	for _, path := range SearchPaths(explicit) {
+		if !util.FileExists(path) {
+			if explicit != "" {
+				return nil, fmt.Errorf("config file not found: %s", path)
+			}
+			continue
+		}
+		return LoadFile(path)
+	}
+
+	cfg := Default()
+	return cfg, cfg.Validate()
+}
+
+// LoadFile parses a single config file on top of the defaults
+func LoadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %v", path, err)
+	}
+
+	cfg := Default()
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %v", path, err)
+	}
+	cfg.Path = path
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate parses durations, normalizes values and reports the first invalid setting
+func (c *Config) Validate() error {
+	c.API.BaseURL = strings.TrimSpace(c.API.BaseURL)
+	if err := validateHTTPURL("api.base_url", c.API.BaseURL); err != nil {
+		return err
+	}
+	if c.API.RetryLimit < 0 {
+		return fmt.Errorf("api.retry_limit: must not be negative (got %d)", c.API.RetryLimit)
+	}
+	if err := c.API.Timeout.parse("api.timeout"); err != nil {
+		return err
+	}
+	if c.API.Timeout.Duration <= 0 {
+		return fmt.Errorf("api.timeout: must be greater than zero")
+	}
+	if c.API.Concurrency < 0 {
+		return fmt.Errorf("api.concurrency: must not be negative (got %d)", c.API.Concurrency)
+	}
+	if _, err := network.ParseRate(c.API.RateLimit); err != nil {
+		return fmt.Errorf("api.rate_limit: %v", err)
+	}
+
+	c.Providers.Default = strings.ToLower(strings.TrimSpace(c.Providers.Default))
+	if c.Providers.Default == "" {
+		return fmt.Errorf("providers.default: must name a provider")
+	}
+	for i, name := range c.Providers.Chain {
+		c.Providers.Chain[i] = strings.ToLower(strings.TrimSpace(name))
+		if c.Providers.Chain[i] == "" {
+			return fmt.Errorf("providers.chain[%d]: empty provider name", i)
+		}
+	}
+	if c.Providers.Consensus && len(c.Providers.Chain) < 2 {
+		return fmt.Errorf("providers.consensus: needs at least two providers in providers.chain")
+	}
+	for name, backend := range c.Providers.Backends {
+		if backend.BaseURL != "" {
+			if err := validateHTTPURL("providers.backends."+name+".base_url", backend.BaseURL); err != nil {
+				return err
+			}
+		}
+		if backend.URL != "" {
+			if err := validateHTTPURL("providers.backends."+name+".url", backend.URL); err != nil {
+				return err
+			}
+		}
+		if _, err := network.ParseRate(backend.RateLimit); err != nil {
+			return fmt.Errorf("providers.backends.%s.rate_limit: %v", name, err)
+		}
+		for i, path := range backend.Databases {
+			backend.Databases[i] = util.ExpandHome(path)
+			if !util.FileExists(backend.Databases[i]) {
+				return fmt.Errorf("providers.backends.%s.databases[%d]: file not found: %s", name, i, path)
+			}
+		}
+	}
+
+	if err := c.Cache.TTL.parse("cache.ttl"); err != nil {
+		return err
+	}
+	if c.Cache.TTL.Duration < 0 {
+		return fmt.Errorf("cache.ttl: must not be negative")
+	}
+	c.Cache.Path = util.ExpandHome(strings.TrimSpace(c.Cache.Path))
+
+	templates := make(map[string]string, len(c.Format.Templates))
+	for name, text := range c.Format.Templates {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			return fmt.Errorf("format.templates: empty template name")
+		}
+		if _, err := formatter.ParseTemplate(text); err != nil {
+			return fmt.Errorf("format.templates.%s: %v", name, err)
+		}
+		templates[name] = text
+	}
+	c.Format.Templates = templates
+
+	c.Format.Default = strings.ToLower(strings.TrimSpace(c.Format.Default))
+	_, named := c.Format.Templates[c.Format.Default]
+	if _, ok := formatter.Lookup(c.Format.Default); c.Format.Default != "" && !ok && !named {
+		return fmt.Errorf("format.default: %v", formatter.UnsupportedFormat(c.Format.Default))
+	}
+
+	c.Network.Proxy = strings.TrimSpace(c.Network.Proxy)
+	if c.Network.Proxy != "" {
+		u, err := url.Parse(c.Network.Proxy)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("network.proxy: invalid URL %q", c.Network.Proxy)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("network.proxy: unsupported scheme %q (use http, https or socks5)", u.Scheme)
+		}
+	}
+
+	for i, server := range c.Network.DNSServers {
+		addr, err := normalizeDNSServer(server)
+		if err != nil {
+			return fmt.Errorf("network.dns_servers[%d]: %v", i, err)
+		}
+		c.Network.DNSServers[i] = addr
+	}
+
+	switch c.UI.ColorTheme {
+	case "", "dark", "light", "none":
+	default:
+		return fmt.Errorf("ui.color_theme: unknown theme %q (use dark, light or none)", c.UI.ColorTheme)
+	}
+
+	return nil
+}
+
+func validateHTTPURL(key, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%s: invalid URL %q", key, raw)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%s: URL must use http or https (got %q)", key, raw)
+	}
+	return nil
+}
+
+// normalizeDNSServer turns "8.8.8.8" into "8.8.8.8:53" and validates host:port forms
+func normalizeDNSServer(server string) (string, error) {
+	server = strings.TrimSpace(server)
+	if ip, err := util.CanonicalIP(server, false); err == nil {
+		// CanonicalIP drops the brackets of "[::1]", which JoinHostPort adds back
+		return net.JoinHostPort(ip, "53"), nil
+	}
+
+	host, port, err := net.SplitHostPort(server)
+	if err != nil || port == "" {
+		return "", fmt.Errorf("invalid DNS server %q", server)
+	}
+	if !util.IsValidIP(host) && !util.IsValidHostname(host) {
+		return "", fmt.Errorf("invalid DNS server %q", server)
+	}
+	return server, nil
+}
+
+func userConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config")
+}
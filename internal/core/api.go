@@ -1,11 +1,16 @@
 package core
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/ODIN7h3C0d3r/Netra/internal/config"
 	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"github.com/ODIN7h3C0d3r/Netra/internal/iana"
 	"github.com/ODIN7h3C0d3r/Netra/internal/network"
+	"github.com/ODIN7h3C0d3r/Netra/internal/provider"
 	"github.com/ODIN7h3C0d3r/Netra/internal/util"
 )
 
@@ -14,60 +19,355 @@ const (
 	RetryBackoffTime  = 3 * time.Second
 	MaxRetries        = 3
 	CacheTTL          = 24 * time.Hour
+	UserAgent         = "Netra/1.0 (+https://github.com/ODIN7h3C0d3r/Netra)"
 )
 
 var (
-	cache = NewIPInfoCache(CacheTTL)
+	cache Cache = NewIPInfoCache(CacheTTL)
+	cfg         = config.Default()
+
+	clientMu sync.Mutex
+	client   *network.CustomHTTPClient
+	active   provider.Provider
+
+	// limiters holds one token bucket per provider so batch, interactive and
+	// chained lookups all draw from the same quota
+	limiterMu sync.Mutex
+	limiters  = make(map[string]*network.RateLimiter)
+
+	// inflight lets duplicate IPs in a batch share one upstream request
+	inflight flightGroup
 )
 
-// GetIPInfo fetches IP information from API or cache
-func GetIPInfo(ip string) (*formatter.IPInfo, error) {
-	// Check cache first
-	if cached, ok := cache.Get(ip); ok {
-		util.LogInfo("Using cached result for %s", ip)
-		return cached, nil
+// Configure applies a loaded configuration to the lookup pipeline.
+// It must be called before the first GetIPInfo to take effect for the cache.
+func Configure(c *config.Config) error {
+	httpClient, err := newHTTPClient(c)
+	if err != nil {
+		return err
 	}
 
-	// Rate limit check
-	if cache.AttemptCount(ip) >= MaxRetries {
-		util.LogWarning("Too many failed attempts for %s. Skipping request.", ip)
-		return nil, fmt.Errorf("too many failed attempts")
+	p, err := buildProvider(c, httpClient)
+	if err != nil {
+		return err
+	}
+
+	newCache := openCache(c)
+
+	clientMu.Lock()
+	defer clientMu.Unlock()
+
+	cache.Close()
+	cfg = c
+	client = httpClient
+	active = p
+	cache = newCache
+	return nil
+}
+
+// openCache returns the persistent cache when caching is enabled. If the
+// cache file cannot be opened the run continues with an in-memory cache.
+func openCache(c *config.Config) Cache {
+	if !c.Cache.Enabled {
+		return NewIPInfoCache(c.Cache.TTL.Duration)
 	}
 
-	cfg := network.HTTPClientConfig{
-		Timeout:    10 * time.Second,
-		RetryLimit: 3,
-		UserAgent:  "Netra/1.0 (+https://github.com/ODIN7h3C0d3r/Netra )",
+	disk, err := OpenConfiguredCache(c)
+	if err != nil {
+		util.LogWarning("Persistent cache disabled: %v", err)
+		return NewIPInfoCache(c.Cache.TTL.Duration)
 	}
+	return disk
+}
 
-	client, err := network.NewCustomHTTPClient(cfg)
+// OpenConfiguredCache opens the on-disk cache at cache.path (or the default location)
+func OpenConfiguredCache(c *config.Config) (*DiskCache, error) {
+	path := c.Cache.Path
+	if path == "" {
+		p, err := DefaultCachePath()
+		if err != nil {
+			return nil, err
+		}
+		path = p
+	}
+	return OpenDiskCache(path, c.Cache.TTL.Duration)
+}
+
+// Close flushes and closes the cache; call it once before exiting
+func Close() error {
+	clientMu.Lock()
+	defer clientMu.Unlock()
+	return cache.Close()
+}
+
+// NewProvider builds a named provider from the config's providers section,
+// rate limited to its quota
+func NewProvider(c *config.Config, name string, httpClient network.HTTPClient) (provider.Provider, error) {
+	return newLimitedProvider(c, name, httpClient, true)
+}
+
+// newLimitedProvider wraps the provider in its shared rate limiter. Without
+// wait, an exhausted quota fails the lookup instead of blocking, so a chain
+// moves on to its next provider.
+func newLimitedProvider(c *config.Config, name string, httpClient network.HTTPClient, wait bool) (provider.Provider, error) {
+	p, err := provider.New(name, providerSettings(c, name), httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	caps := p.Capabilities()
+	if caps.Local {
+		return p, nil
+	}
+
+	rate := caps.Quota
+	for _, override := range []string{c.API.RateLimit, c.Providers.Backends[name].RateLimit} {
+		if override != "" {
+			if rate, err = network.ParseRate(override); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	if rate.IsZero() {
+		return p, nil
+	}
+	return provider.NewRateLimited(p, limiterFor(name, rate), wait), nil
+}
+
+// limiterFor returns the limiter for a provider, replacing it if the rate changed
+func limiterFor(name string, rate network.Rate) *network.RateLimiter {
+	limiterMu.Lock()
+	defer limiterMu.Unlock()
+
+	if l, ok := limiters[name]; ok && l.Rate() == rate {
+		return l
+	}
+	l := network.NewRateLimiter(rate)
+	limiters[name] = l
+	return l
+}
+
+// buildProvider creates the configured provider, or a fallback chain or
+// consensus group when providers.chain lists several
+func buildProvider(c *config.Config, httpClient network.HTTPClient) (provider.Provider, error) {
+	if len(c.Providers.Chain) == 0 {
+		return NewProvider(c, c.Providers.Default, httpClient)
+	}
+
+	// Fallback chains skip a provider whose quota is used up; the last one
+	// (and every member of a consensus group) waits for its next token
+	members := make([]provider.Provider, 0, len(c.Providers.Chain))
+	for i, name := range c.Providers.Chain {
+		wait := c.Providers.Consensus || i == len(c.Providers.Chain)-1
+		p, err := newLimitedProvider(c, name, httpClient, wait)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, p)
+	}
+
+	if c.Providers.Consensus {
+		return provider.NewConsensus(members...), nil
+	}
+	return provider.NewChain(members...), nil
+}
+
+// providerSettings converts a config entry into provider settings
+func providerSettings(c *config.Config, name string) provider.Settings {
+	backend := c.Providers.Backends[name]
+	s := provider.Settings{
+		Type:      backend.Type,
+		BaseURL:   backend.BaseURL,
+		Token:     backend.Token,
+		URL:       backend.URL,
+		Fields:    backend.Fields,
+		Databases: backend.Databases,
+	}
+
+	// api.base_url and api.token predate the providers section and keep configuring ipapi
+	if name == "ipapi" && (s.Type == "" || s.Type == "ipapi") {
+		if s.BaseURL == "" {
+			s.BaseURL = c.API.BaseURL
+		}
+		if s.Token == "" {
+			s.Token = c.API.Token
+		}
+	}
+	return s
+}
+
+// currentProvider returns the configured provider, creating the default one if needed
+func currentProvider() (provider.Provider, error) {
+	clientMu.Lock()
+	defer clientMu.Unlock()
+
+	if active != nil {
+		return active, nil
+	}
+
+	if client == nil {
+		c, err := newHTTPClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+		client = c
+	}
+
+	p, err := buildProvider(cfg, client)
+	if err != nil {
+		return nil, err
+	}
+	active = p
+	return active, nil
+}
+
+func newHTTPClient(c *config.Config) (*network.CustomHTTPClient, error) {
+	// lookupIPInfo retries failed lookups itself, through the rate limiter,
+	// so the client makes a single request
+	httpCfg := network.HTTPClientConfig{
+		Timeout:    c.API.Timeout.Duration,
+		RetryLimit: 1,
+		ProxyURL:   c.Network.Proxy,
+		UserAgent:  UserAgent,
+	}
+
+	httpClient, err := network.NewCustomHTTPClient(httpCfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create HTTP client: %v", err)
 	}
+	return httpClient, nil
+}
+
+// GetIPInfo fetches IP information from API or cache. Concurrent calls for
+// the same IP are coalesced into a single lookup. Cancelling ctx aborts the
+// request and any pending retries.
+func GetIPInfo(ctx context.Context, ip string) (*formatter.IPInfo, error) {
+	// One cache key per address however it was written
+	if canonical, err := util.CanonicalIP(ip, false); err == nil {
+		ip = canonical
+	}
+
+	info, err := inflight.Do(ctx, ip, func() (*formatter.IPInfo, error) {
+		return lookupIPInfo(ctx, ip)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := util.DecodeTransition(ip); ok {
+		info = describeTransition(ctx, ip, info, t)
+	}
+	return info, nil
+}
+
+// describeTransition returns a copy of info carrying the IPv4 address
+// embedded in a transition address and, with api.lookup_embedded, its lookup
+func describeTransition(ctx context.Context, ip string, info *formatter.IPInfo, t util.Transition) *formatter.IPInfo {
+	out := *info
+	out.Transition = t.Mechanism
+	out.EmbeddedIPv4 = t.IPv4
+	out.TeredoServer = t.Server
+	out.TeredoPort = t.Port
+
+	if cfg.API.LookupEmbedded {
+		embedded, err := GetIPInfo(ctx, t.IPv4)
+		if err != nil {
+			util.LogWarning("Failed to look up %s embedded in %s: %v", t.IPv4, ip, err)
+		} else {
+			out.Embedded = embedded
+		}
+	}
+	return &out
+}
+
+// lookupIPInfo classifies the address, then answers from the cache or
+// queries the provider with retries. Addresses that are not globally
+// reachable are answered locally and never sent to a provider.
+func lookupIPInfo(ctx context.Context, ip string) (*formatter.IPInfo, error) {
+	block, ok := iana.Lookup(ip)
+	if ok && !block.Global {
+		util.LogStatus("%s is %s; not looked up", ip, block)
+		return &formatter.IPInfo{IP: ip, AddressType: block.Type, Provider: "iana"}, nil
+	}
+	addressType := block.Type
+
+	p, err := currentProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	// Offline databases are faster than the cache and never fail transiently
+	if p.Capabilities().Local {
+		result, err := p.Lookup(ctx, ip)
+		if result != nil {
+			result.AddressType = addressType
+		}
+		return result, err
+	}
+
+	// Check cache first
+	if cfg.Cache.Enabled {
+		if cached, ok := cache.Get(ip); ok {
+			util.LogStatus("Using cached result for %s", ip)
+			if cached.AddressType != addressType {
+				// Entries cached before address types existed
+				info := *cached
+				info.AddressType = addressType
+				cached = &info
+			}
+			return cached, nil
+		}
+	}
+
+	maxRetries := cfg.API.RetryLimit
+	if maxRetries == 0 {
+		maxRetries = MaxRetries
+	}
+
+	// Rate limit check
+	if cache.AttemptCount(ip) >= maxRetries {
+		util.LogWarning("Too many failed attempts for %s. Skipping request.", ip)
+		return nil, fmt.Errorf("too many failed attempts")
+	}
 
 	var result *formatter.IPInfo
 	var fetchErr error
 
 	// Attempt retries with exponential backoff
-	for attempt := 1; attempt <= MaxRetries; attempt++ {
-		result, fetchErr = network.FetchIPInfo(client, DefaultAPIBaseURL, ip)
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		attemptCtx := ctx
+		if attempt > 1 {
+			attemptCtx = provider.WithRetry(ctx)
+		}
+		result, fetchErr = p.Lookup(attemptCtx, ip)
 		if fetchErr == nil {
 			break
 		}
 
+		// Cancellation is not the IP's fault, so it neither counts as a
+		// failed attempt nor gets retried
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
 		util.LogWarning("Attempt %d failed for %s: %v", attempt, ip, fetchErr)
 		cache.RecordAttempt(ip)
 
-		if attempt < MaxRetries {
-			time.Sleep(RetryBackoffTime * time.Duration(attempt))
+		if attempt < maxRetries {
+			if err := network.SleepContext(ctx, RetryBackoffTime*time.Duration(attempt)); err != nil {
+				return nil, err
+			}
 		}
 	}
 
 	if fetchErr != nil {
 		return nil, fetchErr
 	}
+	result.AddressType = addressType
 
 	// Cache successful result
-	cache.Set(ip, result)
+	if cfg.Cache.Enabled {
+		cache.Set(ip, result)
+	}
 	return result, nil
 }
@@ -7,6 +7,17 @@ import (
 	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
 )
 
+// Cache stores lookup results between requests. IPInfoCache keeps them in
+// memory for one run; DiskCache persists them across runs.
+type Cache interface {
+	Get(ip string) (*formatter.IPInfo, bool)
+	Set(ip string, info *formatter.IPInfo)
+	RecordAttempt(ip string)
+	AttemptCount(ip string) int
+	Sweep()
+	Close() error
+}
+
 // CacheEntry represents a cached IP lookup result
 type CacheEntry struct {
 	IPInfo   *formatter.IPInfo
@@ -93,3 +104,8 @@ func (c *IPInfoCache) Sweep() {
 		}
 	}
 }
+
+// Close releases the cache; the in-memory cache has nothing to flush
+func (c *IPInfoCache) Close() error {
+	return nil
+}
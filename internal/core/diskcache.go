@@ -0,0 +1,537 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"github.com/ODIN7h3C0d3r/Netra/internal/util"
+)
+
+// DefaultCacheFile is the cache file name inside the netra cache directory
+const DefaultCacheFile = "cache.jsonl"
+
+// maxCacheLine bounds a single record when reading the cache file
+const maxCacheLine = 1 << 20
+
+// diskRecord is one line of the cache file. Records are replayed in order:
+// "set" (the default) stores an entry, "del" removes one and "stats" adds
+// hit/miss counters from a finished run.
+type diskRecord struct {
+	Op     string            `json:"op,omitempty"`
+	IP     string            `json:"ip,omitempty"`
+	Info   *formatter.IPInfo `json:"info,omitempty"`
+	Stored time.Time         `json:"stored,omitempty"`
+	Hits   int64             `json:"hits,omitempty"`
+	Misses int64             `json:"misses,omitempty"`
+}
+
+// diskEntry is a cached result and when it was stored
+type diskEntry struct {
+	Info   *formatter.IPInfo
+	Stored time.Time
+}
+
+// DiskCache persists lookup results in a single append-only JSON Lines file
+// under $XDG_CACHE_HOME/netra. Writes append one record while holding an
+// exclusive lock on a sidecar lock file, so parallel netra processes can share
+// the cache; records appended by other processes are replayed before each
+// write. Sweep and Close compact the file once stale records pile up.
+type DiskCache struct {
+	path     string
+	lockPath string
+	ttl      time.Duration
+
+	mutex    sync.RWMutex
+	entries  map[string]*diskEntry
+	attempts map[string]int
+
+	// replay position in the data file and the file it refers to
+	offset  int64
+	file    os.FileInfo
+	records int
+
+	hits        int64
+	misses      int64
+	totalHits   int64
+	totalMisses int64
+}
+
+// DefaultCachePath returns $XDG_CACHE_HOME/netra/cache.jsonl (or ~/.cache/netra/cache.jsonl)
+func DefaultCachePath() (string, error) {
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("cannot determine cache directory: %v", err)
+		}
+		dir = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(dir, "netra", DefaultCacheFile), nil
+}
+
+// OpenDiskCache opens (creating if needed) the cache file at path
+func OpenDiskCache(path string, ttl time.Duration) (*DiskCache, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %v", err)
+	}
+
+	c := &DiskCache{
+		path:     path,
+		lockPath: path + ".lock",
+		ttl:      ttl,
+		entries:  make(map[string]*diskEntry),
+		attempts: make(map[string]int),
+	}
+
+	err := c.withLock(false, func() error {
+		return c.replay()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Path returns the cache file location
+func (c *DiskCache) Path() string {
+	return c.path
+}
+
+// Get retrieves IPInfo from cache if it exists and is not expired
+func (c *DiskCache) Get(ip string) (*formatter.IPInfo, bool) {
+	c.mutex.RLock()
+	entry, found := c.entries[ip]
+	c.mutex.RUnlock()
+
+	if !found || c.expired(entry) {
+		atomic.AddInt64(&c.misses, 1)
+		return nil, false
+	}
+
+	atomic.AddInt64(&c.hits, 1)
+	return entry.Info, true
+}
+
+// Set stores IPInfo in memory and appends it to the cache file
+func (c *DiskCache) Set(ip string, info *formatter.IPInfo) {
+	rec := diskRecord{IP: ip, Info: info, Stored: time.Now().UTC()}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.withLock(true, func() error {
+		if err := c.replay(); err != nil {
+			return err
+		}
+		return c.appendRecords(rec)
+	})
+	if err != nil {
+		// The in-memory copy still serves this run; persisting is best effort
+		util.LogWarning("Failed to write cache %s: %v", c.path, err)
+	}
+
+	// Set after replay, which may have reloaded entries from a compacted file
+	c.entries[ip] = &diskEntry{Info: info, Stored: rec.Stored}
+	delete(c.attempts, ip)
+}
+
+// RecordAttempt records failed attempts to prevent cache stampedes.
+// Attempts are only tracked for the current run.
+func (c *DiskCache) RecordAttempt(ip string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.attempts[ip]++
+}
+
+// AttemptCount returns the number of failed attempts for an IP
+func (c *DiskCache) AttemptCount(ip string) int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.attempts[ip]
+}
+
+// Sweep removes expired entries and rewrites the cache file without them
+func (c *DiskCache) Sweep() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	err := c.withLock(true, func() error {
+		if err := c.replay(); err != nil {
+			return err
+		}
+		for ip, entry := range c.entries {
+			if c.expired(entry) {
+				delete(c.entries, ip)
+			}
+		}
+		return c.compact()
+	})
+	if err != nil {
+		util.LogWarning("Failed to sweep cache %s: %v", c.path, err)
+	}
+}
+
+// Close records this run's hit/miss counters and compacts the file if it
+// holds many more records than live entries
+func (c *DiskCache) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	hits, misses := atomic.SwapInt64(&c.hits, 0), atomic.SwapInt64(&c.misses, 0)
+
+	return c.withLock(true, func() error {
+		if err := c.replay(); err != nil {
+			return err
+		}
+		if hits > 0 || misses > 0 {
+			if err := c.appendRecords(diskRecord{Op: "stats", Hits: hits, Misses: misses}); err != nil {
+				return err
+			}
+		}
+		if c.records > 2*len(c.entries)+1000 {
+			return c.compact()
+		}
+		return nil
+	})
+}
+
+func (c *DiskCache) expired(entry *diskEntry) bool {
+	return time.Now().After(entry.Stored.Add(c.ttl))
+}
+
+// withLock runs fn while holding the cross-process lock on the sidecar lock file
+func (c *DiskCache) withLock(exclusive bool, fn func() error) error {
+	lock, err := os.OpenFile(c.lockPath, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open cache lock: %v", err)
+	}
+	defer lock.Close()
+
+	if err := lockFile(lock, exclusive); err != nil {
+		return fmt.Errorf("failed to lock cache: %v", err)
+	}
+	defer unlockFile(lock)
+
+	return fn()
+}
+
+// replay applies records added to the file since the last replay. If another
+// process compacted the file in the meantime it is replayed from the start.
+// Must be called with the file lock held.
+func (c *DiskCache) replay() error {
+	f, err := os.OpenFile(c.path, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open cache: %v", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if c.file == nil || !os.SameFile(c.file, info) || info.Size() < c.offset {
+		c.entries = make(map[string]*diskEntry)
+		c.offset, c.records = 0, 0
+		c.totalHits, c.totalMisses = 0, 0
+	}
+	c.file = info
+
+	if _, err := f.Seek(c.offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReaderSize(f, 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// A trailing partial line is left by an interrupted write; skip it
+			// and let appendRecords start on a fresh line
+			break
+		}
+		if err != nil {
+			return err
+		}
+		c.offset += int64(len(line))
+		if len(line) > maxCacheLine {
+			continue
+		}
+		c.apply(bytes.TrimSpace(line))
+	}
+	return nil
+}
+
+func (c *DiskCache) apply(line []byte) {
+	if len(line) == 0 {
+		return
+	}
+
+	var rec diskRecord
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return
+	}
+	c.records++
+	c.applyRecord(rec)
+}
+
+func (c *DiskCache) applyRecord(rec diskRecord) {
+	switch rec.Op {
+	case "", "set":
+		if rec.IP == "" || rec.Info == nil {
+			return
+		}
+		if existing, ok := c.entries[rec.IP]; ok && existing.Stored.After(rec.Stored) {
+			return
+		}
+		c.entries[rec.IP] = &diskEntry{Info: rec.Info, Stored: rec.Stored}
+	case "del":
+		delete(c.entries, rec.IP)
+	case "stats":
+		c.totalHits += rec.Hits
+		c.totalMisses += rec.Misses
+	}
+}
+
+// appendRecords writes records at the end of the file. Must be called with
+// the exclusive lock held and after replay, so c.offset is the file size.
+func (c *DiskCache) appendRecords(records ...diskRecord) error {
+	var buf bytes.Buffer
+	for _, rec := range records {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	f, err := os.OpenFile(c.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > c.offset {
+		// Drop the partial line from an interrupted write by starting a new one
+		if _, err := f.Write([]byte("\n")); err != nil {
+			return err
+		}
+		c.offset = info.Size() + 1
+	}
+
+	n, err := f.Write(buf.Bytes())
+	c.offset += int64(n)
+	if err != nil {
+		return err
+	}
+	c.records += len(records)
+	return nil
+}
+
+// compact rewrites the file with only live entries and the accumulated
+// counters, then atomically replaces it. Must be called with the exclusive lock held.
+func (c *DiskCache) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".cache-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	records := 0
+	for ip, entry := range c.entries {
+		if err := enc.Encode(diskRecord{IP: ip, Info: entry.Info, Stored: entry.Stored}); err != nil {
+			tmp.Close()
+			return err
+		}
+		records++
+	}
+	if c.totalHits > 0 || c.totalMisses > 0 {
+		if err := enc.Encode(diskRecord{Op: "stats", Hits: c.totalHits, Misses: c.totalMisses}); err != nil {
+			tmp.Close()
+			return err
+		}
+		records++
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return err
+	}
+	c.file = info
+	c.offset = info.Size()
+	c.records = records
+	return nil
+}
+
+// CacheItem is one cached result as shown by `netra cache` and written by export
+type CacheItem struct {
+	IP     string            `json:"ip"`
+	Info   *formatter.IPInfo `json:"info"`
+	Stored time.Time         `json:"stored"`
+
+	// Expired is computed from the cache TTL and not written on export
+	Expired bool `json:"-"`
+}
+
+// CacheStats summarizes the cache contents and its lifetime hit rate
+type CacheStats struct {
+	Entries   int
+	Expired   int
+	Hits      int64
+	Misses    int64
+	SizeBytes int64
+	Oldest    time.Time
+	Newest    time.Time
+}
+
+// HitRate returns hits as a fraction of all lookups (0 if there were none)
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Items returns all entries, including expired ones, sorted by IP
+func (c *DiskCache) Items() ([]CacheItem, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := c.withLock(false, c.replay); err != nil {
+		return nil, err
+	}
+
+	items := make([]CacheItem, 0, len(c.entries))
+	for ip, entry := range c.entries {
+		items = append(items, CacheItem{IP: ip, Info: entry.Info, Stored: entry.Stored, Expired: c.expired(entry)})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].IP < items[j].IP })
+	return items, nil
+}
+
+// Item returns a single entry even if it has expired
+func (c *DiskCache) Item(ip string) (CacheItem, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	entry, ok := c.entries[ip]
+	if !ok {
+		return CacheItem{}, false
+	}
+	return CacheItem{IP: ip, Info: entry.Info, Stored: entry.Stored, Expired: c.expired(entry)}, true
+}
+
+// Stats reports entry counts, file size, age range and hit rate. The hit rate
+// includes runs that have already closed the cache plus the current one.
+func (c *DiskCache) Stats() (CacheStats, error) {
+	items, err := c.Items()
+	if err != nil {
+		return CacheStats{}, err
+	}
+
+	c.mutex.RLock()
+	stats := CacheStats{
+		Entries: len(items),
+		Hits:    c.totalHits + atomic.LoadInt64(&c.hits),
+		Misses:  c.totalMisses + atomic.LoadInt64(&c.misses),
+	}
+	c.mutex.RUnlock()
+
+	for _, item := range items {
+		if item.Expired {
+			stats.Expired++
+		}
+		if stats.Oldest.IsZero() || item.Stored.Before(stats.Oldest) {
+			stats.Oldest = item.Stored
+		}
+		if item.Stored.After(stats.Newest) {
+			stats.Newest = item.Stored
+		}
+	}
+
+	if info, err := os.Stat(c.path); err == nil {
+		stats.SizeBytes = info.Size()
+	}
+	return stats, nil
+}
+
+// Purge deletes every entry for which match returns true and compacts the file
+func (c *DiskCache) Purge(match func(CacheItem) bool) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	removed := 0
+	err := c.withLock(true, func() error {
+		if err := c.replay(); err != nil {
+			return err
+		}
+		for ip, entry := range c.entries {
+			if match(CacheItem{IP: ip, Info: entry.Info, Stored: entry.Stored, Expired: c.expired(entry)}) {
+				delete(c.entries, ip)
+				removed++
+			}
+		}
+		return c.compact()
+	})
+	return removed, err
+}
+
+// Import adds entries keeping their original store time; entries without
+// one are treated as stored now
+func (c *DiskCache) Import(items []CacheItem) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	records := make([]diskRecord, 0, len(items))
+	for _, item := range items {
+		if item.Stored.IsZero() {
+			item.Stored = time.Now().UTC()
+		}
+		records = append(records, diskRecord{IP: item.IP, Info: item.Info, Stored: item.Stored})
+	}
+
+	return c.withLock(true, func() error {
+		if err := c.replay(); err != nil {
+			return err
+		}
+		if err := c.appendRecords(records...); err != nil {
+			return err
+		}
+		for _, rec := range records {
+			c.applyRecord(rec)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,27 @@
+//go:build !windows
+
+package core
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an advisory flock on f, blocking until it is available
+func lockFile(f *os.File, exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	for {
+		err := syscall.Flock(int(f.Fd()), how)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+// unlockFile releases a lock taken with lockFile
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
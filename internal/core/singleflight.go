@@ -0,0 +1,59 @@
+package core
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+)
+
+// flightCall is a lookup in progress that other callers can wait on
+type flightCall struct {
+	done chan struct{}
+	info *formatter.IPInfo
+	err  error
+}
+
+// flightGroup coalesces concurrent lookups of the same IP so that one
+// upstream request serves every caller that asks while it is in flight
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// Do runs fn for key unless a call for key is already running, in which case
+// it waits for that call (or for ctx to be done) and returns its result.
+// Waiters get their own copy of the result so callers can modify it without
+// affecting each other.
+func (g *flightGroup) Do(ctx context.Context, key string, fn func() (*formatter.IPInfo, error)) (*formatter.IPInfo, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if c.info == nil {
+			return nil, c.err
+		}
+		info := *c.info
+		return &info, c.err
+	}
+
+	c := &flightCall{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	c.info, c.err = fn()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(c.done)
+
+	return c.info, c.err
+}
@@ -1,40 +1,97 @@
 package formatter
 
 import (
+    "bytes"
     "encoding/csv"
     "fmt"
-    "strings"
+    "io"
 )
 
+func init() {
+    Register(&outputFormat{
+        name:       "csv",
+        mime:       "text/csv",
+        extensions: []string{"csv"},
+        streaming:  true,
+        selects:    true,
+        fixed:      true,
+        newWriter: func(w io.Writer, opts Options) (StreamWriter, error) {
+            return newCSVStream(w, opts.Fields)
+        },
+    })
+}
+
 // FormatCSV converts IPInfo slice into CSV format with optional field filtering
 func FormatCSV(data []*IPInfo, fieldsStr string) (string, error) {
+    var buf bytes.Buffer
+    sw, err := newCSVStream(&buf, fieldsStr)
+    if err != nil {
+        return "", err
+    }
+    return formatAll(sw, &buf, data)
+}
+
+// csvStream writes a header row followed by one row per record. The header
+// is written with the first record, whose input columns (if any) name the
+// passthrough columns of every row.
+type csvStream struct {
+    writer *csv.Writer
+    fields []string
+    header bool
+}
+
+func newCSVStream(w io.Writer, fieldsStr string) (*csvStream, error) {
     fields := parseFields(fieldsStr)
     if !validFields(fields) {
-        return "", fmt.Errorf("invalid field(s) specified for CSV")
+        return nil, fmt.Errorf("invalid field(s) specified for CSV")
+    }
+    if len(fields) == 0 {
+        fields = getAllFields()
     }
+    return &csvStream{writer: csv.NewWriter(w), fields: fields}, nil
+}
 
-    var b strings.Builder
-    writer := csv.NewWriter(&b)
+func (s *csvStream) Begin() error {
+    return nil
+}
 
-    // Write header
-    if err := writer.Write(fields); err != nil {
-        return "", err
+func (s *csvStream) writeHeader(info *IPInfo) error {
+    if info == nil {
+        info = &IPInfo{}
     }
+    s.header = true
+    s.fields = expandFields(s.fields, info)
+    return s.writer.Write(s.fields)
+}
 
-    // Write rows
-    for _, info := range data {
-        row := make([]string, len(fields))
-        ipMap := info.ToMap()
-
-        for i, field := range fields {
-            row[i] = fmt.Sprintf("%v", ipMap[field])
+func (s *csvStream) Write(info *IPInfo) error {
+    if !s.header {
+        if err := s.writeHeader(info); err != nil {
+            return err
         }
+    }
 
-        if err := writer.Write(row); err != nil {
-            return "", err
+    row := make([]string, len(s.fields))
+    ipMap := info.ToMap()
+    for i, field := range s.fields {
+        if v, ok := ipMap[field]; ok {
+            row[i] = fieldText(v)
         }
     }
 
-    writer.Flush()
-    return b.String(), nil
-}
\ No newline at end of file
+    if err := s.writer.Write(row); err != nil {
+        return err
+    }
+    s.writer.Flush()
+    return s.writer.Error()
+}
+
+func (s *csvStream) End() error {
+    if !s.header {
+        if err := s.writeHeader(nil); err != nil {
+            return err
+        }
+    }
+    s.writer.Flush()
+    return s.writer.Error()
+}
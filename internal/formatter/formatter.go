@@ -3,6 +3,8 @@ package formatter
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -10,21 +12,70 @@ import (
 // Moved from core/ipinfo.go to avoid import cycles
 // Helper functions also moved here
 type IPInfo struct {
-	IP        string  `json:"ip"`
-	Country   string  `json:"country_name"`
-	Region    string  `json:"region"`
-	City      string  `json:"city"`
-	Latitude  float64 `json:"latitude"`
-	Longitude float64 `json:"longitude"`
-	Timezone  string  `json:"timezone"`
-	ISP       string  `json:"organization"`
-	Postal    string  `json:"postal"`
-	ASN       string  `json:"asn"`
-	IsMobile  bool    `json:"device_is_mobile"`
-	IsProxy   bool    `json:"proxy"`
-	IsHosting bool    `json:"is_hosting"`
-	Org       string  `json:"organization"`
-	Continent string  `json:"continent"`
+	IP          string  `json:"ip"`
+	Country     string  `json:"country"`
+	CountryCode string  `json:"country_code"`
+	Region      string  `json:"region"`
+	City        string  `json:"city"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	Timezone    string  `json:"timezone"`
+	ISP         string  `json:"isp"`
+	Postal      string  `json:"postal"`
+	ASN         string  `json:"asn"`
+	IsMobile    bool    `json:"is_mobile"`
+	IsProxy     bool    `json:"is_proxy"`
+	IsHosting   bool    `json:"is_hosting"`
+	Org         string  `json:"org"`
+	Continent   string  `json:"continent"`
+	Provider    string  `json:"provider"`
+
+	// AddressType is the IANA special-purpose classification: global,
+	// private, loopback, link-local, cgnat, documentation, multicast or reserved
+	AddressType string `json:"address_type,omitempty"`
+
+	// IPv6 transition addresses (IPv4-mapped, 6to4, Teredo, NAT64): the
+	// mechanism and the IPv4 address they embed; for Teredo also the server
+	// and the client's public port. Embedded is the lookup of the IPv4
+	// address when requested.
+	Transition   string  `json:"transition,omitempty"`
+	EmbeddedIPv4 string  `json:"embedded_ipv4,omitempty"`
+	TeredoServer string  `json:"teredo_server,omitempty"`
+	TeredoPort   int     `json:"teredo_port,omitempty"`
+	Embedded     *IPInfo `json:"embedded,omitempty"`
+
+	// Sources records which provider(s) supplied each field and Disagreements
+	// what every provider answered for contested fields (consensus mode only)
+	Sources       map[string]string            `json:"sources,omitempty"`
+	Disagreements map[string]map[string]string `json:"disagreements,omitempty"`
+
+	// Count is how often the IP appeared in the input (only with -unique)
+	Count int `json:"count,omitempty"`
+
+	// Original is the address as written in the input when it was not in
+	// canonical form
+	Original string `json:"original,omitempty"`
+
+	// ResolvedFrom is the hostname, URL or email address the IP was resolved from
+	ResolvedFrom string `json:"resolved_from,omitempty"`
+
+	// Line is the input line an address was extracted from (only with -extract)
+	Line int `json:"line,omitempty"`
+
+	// Log aggregates, filled in by `netra logs`
+	FirstSeen string `json:"first_seen,omitempty"`
+	LastSeen  string `json:"last_seen,omitempty"`
+	TopPaths  string `json:"top_paths,omitempty"`
+	TopUsers  string `json:"top_users,omitempty"`
+
+	// Input holds the columns of a structured input row (-input-format
+	// csv/jsonl), written back out alongside the enrichment fields
+	Input []Column `json:"-"`
+
+	// Traffic totals, filled in by `netra pcap`
+	Packets  int    `json:"packets,omitempty"`
+	Bytes    int64  `json:"bytes,omitempty"`
+	TopPorts string `json:"top_ports,omitempty"`
 }
 
 func (i *IPInfo) FromJSON(data []byte) error {
@@ -39,37 +90,267 @@ func (i *IPInfo) FromJSON(data []byte) error {
 		return fmt.Errorf("failed to parse IP info JSON: %v", err)
 	}
 
-	// Post-processing
-	i.IsHosting = detectHosting(i.ISP, i.ASN)
+	i.Normalize()
 	return nil
 }
 
+// Normalize fills derived fields once a provider has populated the raw data
+func (i *IPInfo) Normalize() {
+	if !i.IsHosting {
+		i.IsHosting = detectHosting(i.ISP+" "+i.Org, i.ASN)
+	}
+}
+
+// SetField assigns a value to the field named as in ToMap, converting
+// strings, numbers and booleans as needed
+func (i *IPInfo) SetField(name string, value interface{}) error {
+	switch name {
+	case "latitude", "longitude":
+		f, err := toFloat(value)
+		if err != nil {
+			return fmt.Errorf("field %s: %v", name, err)
+		}
+		if name == "latitude" {
+			i.Latitude = f
+		} else {
+			i.Longitude = f
+		}
+		return nil
+	case "is_mobile", "is_proxy", "is_hosting":
+		b, err := toBool(value)
+		if err != nil {
+			return fmt.Errorf("field %s: %v", name, err)
+		}
+		switch name {
+		case "is_mobile":
+			i.IsMobile = b
+		case "is_proxy":
+			i.IsProxy = b
+		default:
+			i.IsHosting = b
+		}
+		return nil
+	}
+
+	str := toString(value)
+	switch name {
+	case "ip":
+		i.IP = str
+	case "country":
+		i.Country = str
+	case "country_code":
+		i.CountryCode = str
+	case "region":
+		i.Region = str
+	case "city":
+		i.City = str
+	case "timezone":
+		i.Timezone = str
+	case "isp":
+		i.ISP = str
+	case "postal":
+		i.Postal = str
+	case "asn":
+		i.ASN = str
+	case "org":
+		i.Org = str
+	case "continent":
+		i.Continent = str
+	case "provider":
+		i.Provider = str
+	default:
+		return fmt.Errorf("unknown field: %s", name)
+	}
+	return nil
+}
+
+// Column is one named value of a structured input row
+type Column struct {
+	Name  string
+	Value interface{}
+}
+
+// InputFields names the input columns as they appear in the output. A column
+// whose name clashes with an enrichment field gets an "input_" prefix.
+func (i *IPInfo) InputFields() []string {
+	names := make([]string, len(i.Input))
+	for n, c := range i.Input {
+		names[n] = inputFieldName(c.Name)
+	}
+	return names
+}
+
+func inputFieldName(name string) string {
+	if _, clash := validFieldMap[strings.ToLower(name)]; clash {
+		return "input_" + name
+	}
+	return name
+}
+
 func (i *IPInfo) ToMap() map[string]interface{} {
-	return map[string]interface{}{
-		"ip":         i.IP,
-		"country":    i.Country,
-		"region":     i.Region,
-		"city":       i.City,
-		"latitude":   i.Latitude,
-		"longitude":  i.Longitude,
-		"timezone":   i.Timezone,
-		"isp":        i.ISP,
-		"postal":     i.Postal,
-		"asn":        i.ASN,
-		"is_mobile":  boolToString(i.IsMobile),
-		"is_proxy":   boolToString(i.IsProxy),
-		"is_hosting": boolToString(i.IsHosting),
-		"org":        i.Org,
-		"continent":  i.Continent,
+	m := map[string]interface{}{
+		"ip":           i.IP,
+		"country":      i.Country,
+		"country_code": i.CountryCode,
+		"region":       i.Region,
+		"city":         i.City,
+		"latitude":     i.Latitude,
+		"longitude":    i.Longitude,
+		"timezone":     i.Timezone,
+		"isp":          i.ISP,
+		"postal":       i.Postal,
+		"asn":          i.ASN,
+		"is_mobile":    boolToString(i.IsMobile),
+		"is_proxy":     boolToString(i.IsProxy),
+		"is_hosting":   boolToString(i.IsHosting),
+		"org":          i.Org,
+		"continent":    i.Continent,
+		"provider":     i.Provider,
+		"address_type": i.AddressType,
+	}
+	if len(i.Sources) > 0 {
+		m["sources"] = formatSources(i.Sources)
+	}
+	if len(i.Disagreements) > 0 {
+		m["disagreements"] = formatDisagreements(i.Disagreements)
+	}
+	if i.Count > 0 {
+		m["count"] = i.Count
+	}
+	if i.Original != "" {
+		m["original"] = i.Original
+	}
+	if i.ResolvedFrom != "" {
+		m["resolved_from"] = i.ResolvedFrom
+	}
+	if i.Line > 0 {
+		m["line"] = i.Line
+	}
+	if i.Transition != "" {
+		m["transition"] = i.Transition
+		m["embedded_ipv4"] = i.EmbeddedIPv4
+	}
+	if i.TeredoServer != "" {
+		m["teredo_server"] = i.TeredoServer
+		m["teredo_port"] = i.TeredoPort
+	}
+	if e := i.Embedded; e != nil {
+		m["embedded_country"] = e.Country
+		m["embedded_country_code"] = e.CountryCode
+		m["embedded_asn"] = e.ASN
+		m["embedded_isp"] = e.ISP
+		m["embedded_address_type"] = e.AddressType
+	}
+	if i.Packets > 0 {
+		m["packets"] = i.Packets
+		m["bytes"] = i.Bytes
+	}
+	for k, v := range map[string]string{
+		"first_seen": i.FirstSeen,
+		"last_seen":  i.LastSeen,
+		"top_paths":  i.TopPaths,
+		"top_users":  i.TopUsers,
+		"top_ports":  i.TopPorts,
+	} {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	for _, c := range i.Input {
+		m[inputFieldName(c.Name)] = c.Value
+	}
+	return m
+}
+
+// formatSources flattens provenance into a stable single-line form,
+// e.g. "asn=ipinfo; country=ipapi+ip-api"
+func formatSources(sources map[string]string) string {
+	fields := make([]string, 0, len(sources))
+	for field := range sources {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = field + "=" + sources[field]
+	}
+	return strings.Join(parts, "; ")
+}
+
+// formatDisagreements flattens contested fields, e.g. "city: ipapi=Paris, ipinfo=Lyon"
+func formatDisagreements(d map[string]map[string]string) string {
+	fields := make([]string, 0, len(d))
+	for field := range d {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = field + ": " + strings.ReplaceAll(formatSources(d[field]), "; ", ", ")
 	}
+	return strings.Join(parts, "; ")
 }
 
 // Helper functions
+
+// parseFields splits a -fields value. A list whose entries start with "+"
+// ("+count,+sources") adds those fields to the default set instead of
+// replacing it; "+input" puts the input columns in front of the defaults.
 func parseFields(s string) []string {
+	return parseFieldsFrom(s, getAllFields())
+}
+
+// parseFieldsFrom splits a -fields value; "+field" entries are added to
+// defaults
+func parseFieldsFrom(s string, defaults []string) []string {
 	if s == "" {
 		return nil
 	}
-	return strings.Split(strings.ToLower(s), ",")
+	fields := strings.Split(strings.ToLower(s), ",")
+	if !strings.HasPrefix(fields[0], "+") {
+		return fields
+	}
+
+	var all []string
+	for _, f := range fields {
+		if f == "+input" {
+			all = append(all, "input")
+		}
+	}
+	all = append(all, defaults...)
+	for _, f := range fields {
+		if f != "+input" {
+			all = append(all, strings.TrimPrefix(f, "+"))
+		}
+	}
+	return all
+}
+
+// expandFields replaces the "input" pseudo-field with the record's input columns
+func expandFields(fields []string, info *IPInfo) []string {
+	for n, f := range fields {
+		if f == "input" {
+			expanded := append(append([]string{}, fields[:n]...), info.InputFields()...)
+			return append(expanded, expandFields(fields[n+1:], info)...)
+		}
+	}
+	return fields
+}
+
+// fieldText renders a field value for the text and CSV formats; structured
+// values from JSON input stay JSON
+func fieldText(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		if b, err := json.Marshal(v); err == nil {
+			return string(b)
+		}
+	case nil:
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
 }
 
 func boolToString(b bool) string {
@@ -79,6 +360,58 @@ func boolToString(b bool) string {
 	return "No"
 }
 
+func toString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", t)
+	}
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch t := v.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return t, nil
+	case int:
+		return float64(t), nil
+	case string:
+		if t == "" {
+			return 0, nil
+		}
+		return strconv.ParseFloat(strings.TrimSpace(t), 64)
+	default:
+		return 0, fmt.Errorf("cannot convert %T to number", v)
+	}
+}
+
+func toBool(v interface{}) (bool, error) {
+	switch t := v.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return t, nil
+	case float64:
+		return t != 0, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(t)) {
+		case "", "0", "false", "no":
+			return false, nil
+		case "1", "true", "yes":
+			return true, nil
+		}
+		return false, fmt.Errorf("cannot convert %q to boolean", t)
+	default:
+		return false, fmt.Errorf("cannot convert %T to boolean", v)
+	}
+}
+
 func titleCase(s string) string {
 	return strings.ToUpper(s[:1]) + s[1:]
 }
@@ -99,34 +432,88 @@ func detectHosting(isp, asn string) bool {
 }
 
 var validFieldMap = map[string]bool{
-	"ip":         true,
-	"country":    true,
-	"region":     true,
-	"city":       true,
-	"latitude":   true,
-	"longitude":  true,
-	"timezone":   true,
-	"isp":        true,
-	"postal":     true,
-	"asn":        true,
-	"is_mobile":  true,
-	"is_proxy":   true,
-	"is_hosting": true,
+	"ip":           true,
+	"country":      true,
+	"region":       true,
+	"city":         true,
+	"latitude":     true,
+	"longitude":    true,
+	"timezone":     true,
+	"isp":          true,
+	"postal":       true,
+	"asn":          true,
+	"is_mobile":    true,
+	"is_proxy":     true,
+	"is_hosting":   true,
+	"country_code": true,
+	"org":          true,
+	"continent":    true,
+	"provider":     true,
+	"address_type": true,
+
+	// Valid but only shown when requested with -fields
+	"sources":       false,
+	"disagreements": false,
+	"count":         false,
+	"original":      false,
+	"resolved_from": false,
+	"line":          false,
+	"first_seen":    false,
+	"last_seen":     false,
+	"top_paths":     false,
+	"top_users":     false,
+	"packets":       false,
+	"bytes":         false,
+	"top_ports":     false,
+	"input":         false,
+
+	"transition":            false,
+	"embedded_ipv4":         false,
+	"teredo_server":         false,
+	"teredo_port":           false,
+	"embedded_country":      false,
+	"embedded_country_code": false,
+	"embedded_asn":          false,
+	"embedded_isp":          false,
+	"embedded_address_type": false,
+}
+
+// fieldOrder is the column order used when no -fields selection is given
+var fieldOrder = []string{
+	"ip", "country", "country_code", "region", "city", "postal",
+	"latitude", "longitude", "timezone", "continent",
+	"isp", "org", "asn", "is_mobile", "is_proxy", "is_hosting",
+	"address_type", "provider", "sources", "disagreements", "count", "original", "resolved_from", "line",
+	"first_seen", "last_seen", "top_paths", "top_users",
+	"packets", "bytes", "top_ports",
+	"transition", "embedded_ipv4", "teredo_server", "teredo_port",
+	"embedded_country", "embedded_country_code", "embedded_asn", "embedded_isp",
+	"embedded_address_type",
+}
+
+// IsField reports whether name is an output field
+func IsField(name string) bool {
+	_, ok := validFieldMap[strings.ToLower(name)]
+	return ok
 }
 
 func validFields(fields []string) bool {
 	for _, f := range fields {
-		if !validFieldMap[f] && f != "" {
+		if _, ok := validFieldMap[f]; !ok && f != "" {
 			return false
 		}
 	}
 	return true
 }
 
+// getAllFields returns the fields shown by default (those marked true in
+// validFieldMap) in a stable order
 func getAllFields() []string {
-	fields := make([]string, 0, len(validFieldMap))
-	for k := range validFieldMap {
-		fields = append(fields, k)
+	fields := make([]string, 0, len(fieldOrder))
+	for _, k := range fieldOrder {
+		if validFieldMap[k] {
+			fields = append(fields, k)
+		}
 	}
 	return fields
 }
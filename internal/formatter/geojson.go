@@ -0,0 +1,101 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+func init() {
+	Register(&outputFormat{
+		name:       "geojson",
+		mime:       "application/geo+json",
+		extensions: []string{"geojson"},
+		streaming:  true,
+		selects:    true,
+		newWriter: func(w io.Writer, opts Options) (StreamWriter, error) {
+			return newGeoJSONStream(w, opts.Fields)
+		},
+	})
+}
+
+// FormatGeoJSON converts IPInfo slice into a GeoJSON FeatureCollection with
+// optional field filtering
+func FormatGeoJSON(data []*IPInfo, fieldsStr string) (string, error) {
+	var buf bytes.Buffer
+	sw, err := newGeoJSONStream(&buf, fieldsStr)
+	if err != nil {
+		return "", err
+	}
+	return formatAll(sw, &buf, data)
+}
+
+// geoJSONFeature is one result; Geometry is null when the result has no
+// location (private addresses, failed lookups)
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   *geoJSONPoint          `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// geoJSONStream writes a FeatureCollection of Points, one feature at a time,
+// with the selected fields as properties
+type geoJSONStream struct {
+	w      io.Writer
+	fields []string
+	count  int
+}
+
+func newGeoJSONStream(w io.Writer, fieldsStr string) (*geoJSONStream, error) {
+	fields := parseFields(fieldsStr)
+	if !validFields(fields) {
+		return nil, fmt.Errorf("invalid field(s) specified for GeoJSON")
+	}
+	return &geoJSONStream{w: w, fields: fields}, nil
+}
+
+func (s *geoJSONStream) Begin() error {
+	_, err := io.WriteString(s.w, "{\n  \"type\": \"FeatureCollection\",\n  \"features\": [")
+	return err
+}
+
+func (s *geoJSONStream) Write(info *IPInfo) error {
+	feature := geoJSONFeature{Type: "Feature", Properties: selectFields(info, s.fields)}
+	if hasLocation(info) {
+		// GeoJSON positions are longitude first
+		feature.Geometry = &geoJSONPoint{Type: "Point", Coordinates: [2]float64{info.Longitude, info.Latitude}}
+	}
+	data, err := json.MarshalIndent(feature, "    ", "  ")
+	if err != nil {
+		return err
+	}
+
+	sep := "\n    "
+	if s.count > 0 {
+		sep = ",\n    "
+	}
+	s.count++
+	_, err = io.WriteString(s.w, sep+string(data))
+	return err
+}
+
+func (s *geoJSONStream) End() error {
+	end := "\n  ]\n}\n"
+	if s.count == 0 {
+		end = "]\n}\n"
+	}
+	_, err := io.WriteString(s.w, end)
+	return err
+}
+
+// hasLocation reports whether a result carries coordinates; providers leave
+// both at zero when they have none
+func hasLocation(info *IPInfo) bool {
+	return info.Latitude != 0 || info.Longitude != 0
+}
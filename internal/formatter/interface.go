@@ -1,21 +1,15 @@
 package formatter
 
 import (
-	"fmt"
+	"bytes"
 )
 
-// Format converts IPInfo slice into the specified format (text/json/csv/yaml)
+// Format converts IPInfo slice into the specified registered format
 func Format(data []*IPInfo, format, fields string) (string, error) {
-	switch format {
-	case "csv":
-		return FormatCSV(data, fields)
-	case "json":
-		return FormatJSON(data, fields)
-	case "yaml":
-		return FormatYAML(data, fields)
-	case "text", "":
-		return FormatText(data, fields)
-	default:
-		return "", fmt.Errorf("unsupported format: %s", format)
+	var buf bytes.Buffer
+	sw, err := NewStreamWriter(&buf, format, fields)
+	if err != nil {
+		return "", err
 	}
+	return formatAll(sw, &buf, data)
 }
@@ -1,37 +1,75 @@
 package formatter
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 )
 
+func init() {
+	Register(&outputFormat{
+		name:       "json",
+		mime:       "application/json",
+		extensions: []string{"json"},
+		streaming:  true,
+		selects:    true,
+		newWriter: func(w io.Writer, opts Options) (StreamWriter, error) {
+			return newJSONStream(w, opts.Fields)
+		},
+	})
+}
+
 // FormatJSON converts IPInfo slice into JSON format with optional field filtering
 func FormatJSON(data []*IPInfo, fieldsStr string) (string, error) {
+	var buf bytes.Buffer
+	sw, err := newJSONStream(&buf, fieldsStr)
+	if err != nil {
+		return "", err
+	}
+	return formatAll(sw, &buf, data)
+}
+
+// jsonStream writes an indented JSON array one element at a time
+type jsonStream struct {
+	w      io.Writer
+	fields []string
+	count  int
+}
+
+func newJSONStream(w io.Writer, fieldsStr string) (*jsonStream, error) {
 	fields := parseFields(fieldsStr)
 	if !validFields(fields) {
-		return "", fmt.Errorf("invalid field(s) specified for JSON")
+		return nil, fmt.Errorf("invalid field(s) specified for JSON")
 	}
+	return &jsonStream{w: w, fields: fields}, nil
+}
 
-	var result []map[string]interface{}
-
-	for _, info := range data {
-		raw := info.ToMap()
+func (s *jsonStream) Begin() error {
+	_, err := io.WriteString(s.w, "[")
+	return err
+}
 
-		if len(fields) > 0 {
-			filtered := make(map[string]interface{})
-			for _, f := range fields {
-				filtered[f] = raw[f]
-			}
-			result = append(result, filtered)
-		} else {
-			result = append(result, raw)
-		}
+func (s *jsonStream) Write(info *IPInfo) error {
+	jsonData, err := json.MarshalIndent(selectFields(info, s.fields), "  ", "  ")
+	if err != nil {
+		return err
 	}
 
-	jsonData, err := json.MarshalIndent(result, "", "  ")
-	if err != nil {
-		return "", err
+	sep := "\n  "
+	if s.count > 0 {
+		sep = ",\n  "
 	}
+	s.count++
+	_, err = io.WriteString(s.w, sep+string(jsonData))
+	return err
+}
 
-	return string(jsonData), nil
+func (s *jsonStream) End() error {
+	end := "\n]\n"
+	if s.count == 0 {
+		end = "]\n"
+	}
+	_, err := io.WriteString(s.w, end)
+	return err
 }
@@ -0,0 +1,89 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+func init() {
+	Register(&outputFormat{
+		name:       "jsonl",
+		mime:       "application/x-ndjson",
+		extensions: []string{"jsonl", "ndjson"},
+		streaming:  true,
+		selects:    true,
+		appendable: true,
+		newWriter: func(w io.Writer, opts Options) (StreamWriter, error) {
+			return newJSONLStream(w, opts.Fields)
+		},
+	})
+}
+
+// FormatJSONL converts IPInfo slice into JSON Lines with optional field filtering
+func FormatJSONL(data []*IPInfo, fieldsStr string) (string, error) {
+	var buf bytes.Buffer
+	sw, err := newJSONLStream(&buf, fieldsStr)
+	if err != nil {
+		return "", err
+	}
+	return formatAll(sw, &buf, data)
+}
+
+// jsonlStream writes one compact JSON object per line. It has no header or
+// footer, so runs can append to the same file.
+type jsonlStream struct {
+	w      io.Writer
+	fields []string
+}
+
+func newJSONLStream(w io.Writer, fieldsStr string) (*jsonlStream, error) {
+	fields := parseFields(fieldsStr)
+	if !validFields(fields) {
+		return nil, fmt.Errorf("invalid field(s) specified for JSON Lines")
+	}
+	return &jsonlStream{w: w, fields: fields}, nil
+}
+
+func (s *jsonlStream) Begin() error {
+	return nil
+}
+
+func (s *jsonlStream) Write(info *IPInfo) error {
+	return s.writeLine(selectFields(info, s.fields))
+}
+
+// WriteError writes {"ip": ..., "error": ...} for a failed lookup, keeping
+// the input columns so the record can be matched to its source
+func (s *jsonlStream) WriteError(info *IPInfo, lookupErr error) error {
+	rec := make(map[string]interface{}, len(info.Input)+5)
+	for _, c := range info.Input {
+		rec[inputFieldName(c.Name)] = c.Value
+	}
+	if info.Original != "" {
+		rec["original"] = info.Original
+	}
+	if info.ResolvedFrom != "" {
+		rec["resolved_from"] = info.ResolvedFrom
+	}
+	if info.Line > 0 {
+		rec["line"] = info.Line
+	}
+	rec["ip"] = info.IP
+	rec["error"] = lookupErr.Error()
+	return s.writeLine(rec)
+}
+
+func (s *jsonlStream) writeLine(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(append(data, '\n'))
+	return err
+}
+
+func (s *jsonlStream) End() error {
+	return nil
+}
@@ -0,0 +1,214 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register(&outputFormat{
+		name:       "kml",
+		mime:       "application/vnd.google-earth.kml+xml",
+		extensions: []string{"kml"},
+		streaming:  false,
+		selects:    true,
+		newWriter: func(w io.Writer, opts Options) (StreamWriter, error) {
+			return newKMLStream(w, opts)
+		},
+	})
+}
+
+// KMLGroups are the values accepted by Options.GroupBy
+var KMLGroups = []string{"country", "asn"}
+
+// unknownFolder holds the placemarks without a country or ASN to group by
+const unknownFolder = "Unknown"
+
+// FormatKML converts IPInfo slice into a KML document with optional field filtering
+func FormatKML(data []*IPInfo, fieldsStr string) (string, error) {
+	var buf bytes.Buffer
+	sw, err := newKMLStream(&buf, Options{Fields: fieldsStr})
+	if err != nil {
+		return "", err
+	}
+	return formatAll(sw, &buf, data)
+}
+
+type kmlDocument struct {
+	XMLName  xml.Name `xml:"kml"`
+	NS       string   `xml:"xmlns,attr"`
+	Document struct {
+		Name       string         `xml:"name"`
+		Folders    []kmlFolder    `xml:"Folder"`
+		Placemarks []kmlPlacemark `xml:"Placemark"`
+	} `xml:"Document"`
+}
+
+type kmlFolder struct {
+	Name       string         `xml:"name"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name         string    `xml:"name"`
+	Description  string    `xml:"description,omitempty"`
+	ExtendedData []kmlData `xml:"ExtendedData>Data,omitempty"`
+	Point        *kmlPoint `xml:"Point,omitempty"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+// kmlStream collects every record and writes the document at End, when the
+// placemarks can be sorted and grouped into folders
+type kmlStream struct {
+	w      io.Writer
+	opts   Options
+	fields []string
+	rows   []*IPInfo
+}
+
+func newKMLStream(w io.Writer, opts Options) (*kmlStream, error) {
+	fields := parseFields(opts.Fields)
+	if !validFields(fields) {
+		return nil, fmt.Errorf("invalid field(s) specified for KML")
+	}
+	if opts.GroupBy != "" && opts.GroupBy != "country" && opts.GroupBy != "asn" {
+		return nil, fmt.Errorf("cannot group KML by %q (available: %s)", opts.GroupBy, strings.Join(KMLGroups, ", "))
+	}
+	return &kmlStream{w: w, opts: opts, fields: fields}, nil
+}
+
+func (s *kmlStream) Begin() error {
+	return nil
+}
+
+func (s *kmlStream) Write(info *IPInfo) error {
+	s.rows = append(s.rows, info)
+	return nil
+}
+
+func (s *kmlStream) End() error {
+	if s.opts.Sort != "" {
+		if err := s.sortRows(); err != nil {
+			return err
+		}
+	}
+
+	doc := kmlDocument{NS: "http://www.opengis.net/kml/2.2"}
+	doc.Document.Name = "netra"
+	folders := make(map[string]*kmlFolder)
+	for _, info := range s.rows {
+		placemark := s.placemark(info)
+		if s.opts.GroupBy == "" {
+			doc.Document.Placemarks = append(doc.Document.Placemarks, placemark)
+			continue
+		}
+		name := folderName(info, s.opts.GroupBy)
+		if folders[name] == nil {
+			folders[name] = &kmlFolder{Name: name}
+		}
+		folders[name].Placemarks = append(folders[name].Placemarks, placemark)
+	}
+
+	names := make([]string, 0, len(folders))
+	for name := range folders {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if (names[i] == unknownFolder) != (names[j] == unknownFolder) {
+			return names[j] == unknownFolder
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		doc.Document.Folders = append(doc.Document.Folders, *folders[name])
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(s.w, xml.Header+string(data)+"\n")
+	return err
+}
+
+// sortRows orders the records by the -sort field
+func (s *kmlStream) sortRows() error {
+	records := make([]map[string]interface{}, len(s.rows))
+	for r, info := range s.rows {
+		records[r] = info.ToMap()
+	}
+	key, desc, err := sortKey(records, s.opts.Sort)
+	if err != nil {
+		return err
+	}
+	order := make([]int, len(s.rows))
+	for r := range order {
+		order[r] = r
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := records[order[i]][key], records[order[j]][key]
+		if desc {
+			return lessValue(b, a)
+		}
+		return lessValue(a, b)
+	})
+	rows := make([]*IPInfo, len(s.rows))
+	for r, o := range order {
+		rows[r] = s.rows[o]
+	}
+	s.rows = rows
+	return nil
+}
+
+// placemark describes one result: a "Field: value" balloon for Google Earth
+// and the same fields as ExtendedData for GIS tools
+func (s *kmlStream) placemark(info *IPInfo) kmlPlacemark {
+	m := info.ToMap()
+	fields := s.fields
+	if len(fields) == 0 {
+		fields = getAllFields()
+	}
+	fields = expandFields(fields, info)
+
+	p := kmlPlacemark{Name: info.IP}
+	var lines []string
+	for _, f := range fields {
+		v := fieldText(m[f])
+		p.ExtendedData = append(p.ExtendedData, kmlData{Name: f, Value: v})
+		if v != "" {
+			lines = append(lines, html.EscapeString(titleCase(f)+": "+v))
+		}
+	}
+	p.Description = strings.Join(lines, "<br/>")
+	if hasLocation(info) {
+		// KML coordinates are longitude,latitude
+		p.Point = &kmlPoint{Coordinates: strconv.FormatFloat(info.Longitude, 'f', -1, 64) + "," + strconv.FormatFloat(info.Latitude, 'f', -1, 64)}
+	}
+	return p
+}
+
+// folderName is the folder a result is grouped into
+func folderName(info *IPInfo, groupBy string) string {
+	name := info.Country
+	if groupBy == "asn" {
+		name = info.ASN
+	}
+	if name = strings.TrimSpace(name); name == "" {
+		return unknownFolder
+	}
+	return name
+}
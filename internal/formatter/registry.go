@@ -0,0 +1,158 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Formatter is an output format. Formats register themselves with Register
+// so -format validation, help text and -output extension detection all come
+// from the registry.
+type Formatter interface {
+	// Name is the value given to -format
+	Name() string
+
+	// MIMEType is the media type of the output
+	MIMEType() string
+
+	// Extensions lists the file extensions (without the dot) that select
+	// the format for -output, preferred first
+	Extensions() []string
+
+	// Streaming reports whether records are written as they arrive; other
+	// formats hold them back until the end of the output and may sort them
+	Streaming() bool
+
+	// SelectsFields reports whether the format honours -fields
+	SelectsFields() bool
+
+	// FixedColumns reports whether every record is written under one
+	// header, so the columns must be settled before the first record
+	FixedColumns() bool
+
+	// Appendable reports whether output can be appended to an existing
+	// file of the same format and still be valid
+	Appendable() bool
+
+	// NewWriter returns a writer producing the format on w
+	NewWriter(w io.Writer, opts Options) (StreamWriter, error)
+}
+
+// Options configures a stream writer. Fields applies to most formats; the
+// rest is used by the formats that render for a terminal (table), the
+// template format or the KML format.
+type Options struct {
+	Fields string
+
+	// Sort orders the records by a field, descending with a leading "-";
+	// only formats that are not Streaming can sort
+	Sort string
+
+	// Width is the terminal width to fit the output to; 0 means no limit
+	Width int
+
+	// ASCII draws borders with plain ASCII instead of Unicode box characters
+	ASCII bool
+
+	// Color highlights notable cells with ANSI colors
+	Color bool
+
+	// Template is the text/template source for the template format
+	Template string
+
+	// GroupBy puts KML placemarks into folders by "country" or "asn"
+	GroupBy string
+}
+
+// outputFormat is the Formatter implementation used by the built-in formats
+type outputFormat struct {
+	name       string
+	mime       string
+	extensions []string
+	streaming  bool
+	selects    bool
+	fixed      bool
+	appendable bool
+	newWriter  func(w io.Writer, opts Options) (StreamWriter, error)
+}
+
+func (f *outputFormat) Name() string         { return f.name }
+func (f *outputFormat) MIMEType() string     { return f.mime }
+func (f *outputFormat) Extensions() []string { return f.extensions }
+func (f *outputFormat) Streaming() bool      { return f.streaming }
+func (f *outputFormat) SelectsFields() bool  { return f.selects }
+func (f *outputFormat) FixedColumns() bool   { return f.fixed }
+func (f *outputFormat) Appendable() bool     { return f.appendable }
+
+func (f *outputFormat) NewWriter(w io.Writer, opts Options) (StreamWriter, error) {
+	return f.newWriter(w, opts)
+}
+
+// DefaultFormat is used when no format is given
+const DefaultFormat = "text"
+
+var registry = make(map[string]Formatter)
+
+// Register adds an output format; registering a name twice panics
+func Register(f Formatter) {
+	name := strings.ToLower(f.Name())
+	if _, dup := registry[name]; dup {
+		panic(fmt.Sprintf("formatter: format %q registered twice", name))
+	}
+	registry[name] = f
+}
+
+// Lookup returns the format registered under name; an empty name is the
+// default format
+func Lookup(name string) (Formatter, bool) {
+	if name == "" {
+		name = DefaultFormat
+	}
+	f, ok := registry[strings.ToLower(strings.TrimSpace(name))]
+	return f, ok
+}
+
+// Formats returns the registered formats sorted by name
+func Formats() []Formatter {
+	out := make([]Formatter, 0, len(registry))
+	for _, f := range registry {
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name() < out[j].Name() })
+	return out
+}
+
+// Names returns the registered format names, sorted
+func Names() []string {
+	formats := Formats()
+	names := make([]string, len(formats))
+	for i, f := range formats {
+		names[i] = f.Name()
+	}
+	return names
+}
+
+// ForPath returns the format implied by a file name's extension
+// ("results.csv" is csv)
+func ForPath(path string) (Formatter, bool) {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	if ext == "" {
+		return nil, false
+	}
+	for _, f := range Formats() {
+		for _, e := range f.Extensions() {
+			if e == ext {
+				return f, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// UnsupportedFormat returns the error for a format name that is not registered
+func UnsupportedFormat(name string) error {
+	return fmt.Errorf("unsupported format %q (available: %s)", name, strings.Join(Names(), ", "))
+}
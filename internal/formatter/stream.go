@@ -0,0 +1,74 @@
+package formatter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StreamWriter emits results one record at a time so large batches can be
+// written while lookups are still running. Begin is called once before the
+// first record and End once after the last; End must be called even if no
+// record was written so the output is well-formed.
+type StreamWriter interface {
+	Begin() error
+	Write(info *IPInfo) error
+	End() error
+}
+
+// ErrorWriter is implemented by stream writers that also record failed
+// lookups; info carries the address and its input columns
+type ErrorWriter interface {
+	WriteError(info *IPInfo, err error) error
+}
+
+// NewStreamWriter returns the writer for a registered format
+func NewStreamWriter(w io.Writer, format, fields string) (StreamWriter, error) {
+	return NewStreamWriterWith(w, format, Options{Fields: fields})
+}
+
+// NewStreamWriterWith returns the writer for a registered format with the
+// given options
+func NewStreamWriterWith(w io.Writer, format string, opts Options) (StreamWriter, error) {
+	f, ok := Lookup(format)
+	if !ok {
+		return nil, UnsupportedFormat(format)
+	}
+	if opts.Sort != "" && f.Streaming() {
+		return nil, fmt.Errorf("the %s format writes results as they arrive and cannot sort them", f.Name())
+	}
+	return f.NewWriter(w, opts)
+}
+
+// formatAll runs data through a stream writer and returns the output without
+// its final newline, matching the buffered Format* functions
+func formatAll(sw StreamWriter, buf *bytes.Buffer, data []*IPInfo) (string, error) {
+	if err := sw.Begin(); err != nil {
+		return "", err
+	}
+	for _, info := range data {
+		if err := sw.Write(info); err != nil {
+			return "", err
+		}
+	}
+	if err := sw.End(); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// selectFields returns the requested fields of a record, or all of them
+// when no selection was made
+func selectFields(info *IPInfo, fields []string) map[string]interface{} {
+	raw := info.ToMap()
+	if len(fields) == 0 {
+		return raw
+	}
+	fields = expandFields(fields, info)
+	filtered := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		filtered[f] = raw[f]
+	}
+	return filtered
+}
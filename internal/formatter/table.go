@@ -0,0 +1,303 @@
+package formatter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/util"
+)
+
+func init() {
+	Register(&outputFormat{
+		name:      "table",
+		mime:      "text/plain",
+		streaming: false,
+		selects:   true,
+		fixed:     true,
+		newWriter: func(w io.Writer, opts Options) (StreamWriter, error) {
+			return newTableStream(w, opts)
+		},
+	})
+}
+
+// tableFields are the columns shown when no -fields selection is given;
+// "+field" adds to them
+var tableFields = []string{"ip", "country", "city", "isp", "asn", "is_proxy", "is_hosting", "address_type"}
+
+// minColumnWidth is the narrowest a column is truncated to when fitting the
+// terminal width
+const minColumnWidth = 4
+
+// ANSI colors for highlighted cells
+const (
+	ansiReset  = "\033[0m"
+	ansiRed    = "\033[31m"
+	ansiYellow = "\033[33m"
+	ansiCyan   = "\033[36m"
+	ansiGray   = "\033[90m"
+)
+
+// cellColor picks the color of a notable cell, or "" for none
+func cellColor(field, value string) string {
+	switch field {
+	case "is_proxy":
+		if value == "Yes" {
+			return ansiRed
+		}
+	case "is_hosting":
+		if value == "Yes" {
+			return ansiYellow
+		}
+	case "is_mobile":
+		if value == "Yes" {
+			return ansiCyan
+		}
+	case "address_type":
+		if value != "" && value != "global" {
+			return ansiGray
+		}
+	}
+	return ""
+}
+
+// tableBorder holds the characters a table is drawn with
+type tableBorder struct {
+	horizontal, vertical               string
+	topLeft, topMid, topRight          string
+	midLeft, midMid, midRight          string
+	bottomLeft, bottomMid, bottomRight string
+}
+
+var (
+	boxBorder = tableBorder{
+		horizontal: "─", vertical: "│",
+		topLeft: "┌", topMid: "┬", topRight: "┐",
+		midLeft: "├", midMid: "┼", midRight: "┤",
+		bottomLeft: "└", bottomMid: "┴", bottomRight: "┘",
+	}
+	asciiBorder = tableBorder{
+		horizontal: "-", vertical: "|",
+		topLeft: "+", topMid: "+", topRight: "+",
+		midLeft: "+", midMid: "+", midRight: "+",
+		bottomLeft: "+", bottomMid: "+", bottomRight: "+",
+	}
+)
+
+// FormatTable converts IPInfo slice into an aligned table with optional field filtering
+func FormatTable(data []*IPInfo, fieldsStr string) (string, error) {
+	var buf bytes.Buffer
+	sw, err := newTableStream(&buf, Options{Fields: fieldsStr})
+	if err != nil {
+		return "", err
+	}
+	return formatAll(sw, &buf, data)
+}
+
+// tableStream collects every record and draws one row per record at End,
+// once the column widths are known
+type tableStream struct {
+	w      io.Writer
+	opts   Options
+	fields []string
+	rows   []*IPInfo
+}
+
+func newTableStream(w io.Writer, opts Options) (*tableStream, error) {
+	fields := parseFieldsFrom(opts.Fields, tableFields)
+	if !validFields(fields) {
+		return nil, fmt.Errorf("invalid field(s) specified for table")
+	}
+	if len(fields) == 0 {
+		fields = tableFields
+	}
+	return &tableStream{w: w, opts: opts, fields: fields}, nil
+}
+
+func (s *tableStream) Begin() error {
+	return nil
+}
+
+func (s *tableStream) Write(info *IPInfo) error {
+	s.rows = append(s.rows, info)
+	return nil
+}
+
+func (s *tableStream) End() error {
+	var sample *IPInfo
+	if len(s.rows) > 0 {
+		sample = s.rows[0]
+	} else {
+		sample = &IPInfo{}
+	}
+	fields := expandFields(s.fields, sample)
+
+	records := make([]map[string]interface{}, len(s.rows))
+	for r, info := range s.rows {
+		records[r] = info.ToMap()
+	}
+	if s.opts.Sort != "" {
+		if err := sortRecords(records, s.opts.Sort); err != nil {
+			return err
+		}
+	}
+
+	cells := make([][]string, len(records))
+	for r, m := range records {
+		cells[r] = make([]string, len(fields))
+		for c, f := range fields {
+			cells[r][c] = strings.ReplaceAll(fieldText(m[f]), "\n", " ")
+		}
+	}
+
+	headers := make([]string, len(fields))
+	for c, f := range fields {
+		headers[c] = strings.ToUpper(f)
+	}
+	widths := columnWidths(headers, cells, s.opts.Width)
+
+	border := boxBorder
+	if s.opts.ASCII {
+		border = asciiBorder
+	}
+
+	var b strings.Builder
+	b.WriteString(rule(widths, border.topLeft, border.topMid, border.topRight, border.horizontal))
+	b.WriteString(s.line(headers, nil, widths, border))
+	b.WriteString(rule(widths, border.midLeft, border.midMid, border.midRight, border.horizontal))
+	for _, row := range cells {
+		b.WriteString(s.line(row, fields, widths, border))
+	}
+	b.WriteString(rule(widths, border.bottomLeft, border.bottomMid, border.bottomRight, border.horizontal))
+
+	_, err := io.WriteString(s.w, b.String())
+	return err
+}
+
+// line draws one row; fields is nil for the header, which is never colored
+func (s *tableStream) line(row, fields []string, widths []int, border tableBorder) string {
+	var b strings.Builder
+	b.WriteString(border.vertical)
+	for c, text := range row {
+		text = util.Truncate(text, widths[c])
+		pad := strings.Repeat(" ", widths[c]-utf8.RuneCountInString(text))
+		if fields != nil && s.opts.Color {
+			if color := cellColor(fields[c], row[c]); color != "" {
+				text = color + text + ansiReset
+			}
+		}
+		b.WriteString(" " + text + pad + " " + border.vertical)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// rule draws a horizontal border line
+func rule(widths []int, left, mid, right, horizontal string) string {
+	parts := make([]string, len(widths))
+	for c, w := range widths {
+		parts[c] = strings.Repeat(horizontal, w+2)
+	}
+	return left + strings.Join(parts, mid) + right + "\n"
+}
+
+// columnWidths sizes each column to its widest cell, then narrows the widest
+// columns until the table fits in limit characters (0 for no limit)
+func columnWidths(headers []string, cells [][]string, limit int) []int {
+	widths := make([]int, len(headers))
+	for c, h := range headers {
+		widths[c] = utf8.RuneCountInString(h)
+	}
+	for _, row := range cells {
+		for c, text := range row {
+			if n := utf8.RuneCountInString(text); n > widths[c] {
+				widths[c] = n
+			}
+		}
+	}
+	if limit <= 0 {
+		return widths
+	}
+
+	// Each column takes its width plus a space either side and one border
+	total := 1
+	for _, w := range widths {
+		total += w + 3
+	}
+	for total > limit {
+		widest := 0
+		for c, w := range widths {
+			if w > widths[widest] {
+				widest = c
+			}
+		}
+		if widths[widest] <= minColumnWidth {
+			break
+		}
+		widths[widest]--
+		total--
+	}
+	return widths
+}
+
+// sortRecords orders records by the field or input column named in key
+// ("-country" for descending), comparing addresses and numbers by value
+func sortRecords(records []map[string]interface{}, key string) error {
+	key, desc, err := sortKey(records, key)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		if desc {
+			return lessValue(records[j][key], records[i][key])
+		}
+		return lessValue(records[i][key], records[j][key])
+	})
+	return nil
+}
+
+// sortKey resolves a -sort value against the records, returning the field
+// or input column to sort by and whether the order is descending
+func sortKey(records []map[string]interface{}, key string) (string, bool, error) {
+	desc := strings.HasPrefix(key, "-")
+	key = strings.TrimPrefix(key, "-")
+	if _, ok := validFieldMap[strings.ToLower(key)]; ok {
+		return strings.ToLower(key), desc, nil
+	}
+	if !hasColumn(records, key) {
+		return "", false, fmt.Errorf("cannot sort by unknown field %q", key)
+	}
+	return key, desc, nil
+}
+
+// lessValue orders two field values, comparing addresses and numbers by value
+// and anything else as case-insensitive text
+func lessValue(va, vb interface{}) bool {
+	a, b := fieldText(va), fieldText(vb)
+	if x, err := netip.ParseAddr(a); err == nil {
+		if y, err := netip.ParseAddr(b); err == nil {
+			return x.Less(y)
+		}
+	}
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		return x < y
+	}
+	return strings.ToLower(a) < strings.ToLower(b)
+}
+
+// hasColumn reports whether any record has a value for key
+func hasColumn(records []map[string]interface{}, key string) bool {
+	for _, m := range records {
+		if _, ok := m[key]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package formatter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/util"
+)
+
+func init() {
+	Register(newTemplateFormat("template", ""))
+}
+
+// templateFuncs are the helpers available to output templates, on top of
+// text/template's built-ins (printf, len, index, ...)
+var templateFuncs = template.FuncMap{
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"default":  defaultValue,
+	"join":     join,
+	"flag":     countryFlag,
+	"truncate": truncate,
+}
+
+// ParseTemplate parses an output template, in which the record is the
+// IPInfo ({{.IP}} {{.Country}}) and the helpers upper, lower, default, join,
+// flag and truncate are available
+func ParseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("output").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	// Catch misspelled fields before any lookup is made; other errors may
+	// depend on the data, so they only surface per record
+	sample := &IPInfo{Embedded: &IPInfo{}}
+	if err := tmpl.Execute(io.Discard, sample); err != nil && strings.Contains(err.Error(), "can't evaluate field") {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// named records the formats added by RegisterTemplate
+var named = make(map[string]bool)
+
+// RegisterTemplate makes a named template available as a format of its own
+// (-format ticket). Registering the same name again replaces the template;
+// built-in format names cannot be reused.
+func RegisterTemplate(name, text string) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if _, err := ParseTemplate(text); err != nil {
+		return fmt.Errorf("template %q: %v", name, err)
+	}
+	if _, taken := registry[name]; taken && !named[name] {
+		return fmt.Errorf("template %q: %s is a built-in format", name, name)
+	}
+	delete(registry, name)
+	Register(newTemplateFormat(name, text))
+	named[name] = true
+	return nil
+}
+
+// newTemplateFormat returns a template format; with an empty text the
+// template is taken from Options.Template
+func newTemplateFormat(name, text string) Formatter {
+	return &outputFormat{
+		name:       name,
+		mime:       "text/plain",
+		streaming:  true,
+		appendable: true,
+		newWriter: func(w io.Writer, opts Options) (StreamWriter, error) {
+			if text != "" {
+				return newTemplateStream(w, text)
+			}
+			if opts.Template == "" {
+				return nil, fmt.Errorf("the template format needs -template or -template-file")
+			}
+			return newTemplateStream(w, opts.Template)
+		},
+	}
+}
+
+// templateStream writes one rendered template per record, each ending in a
+// newline
+type templateStream struct {
+	w    io.Writer
+	tmpl *template.Template
+}
+
+func newTemplateStream(w io.Writer, text string) (*templateStream, error) {
+	tmpl, err := ParseTemplate(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %v", err)
+	}
+	return &templateStream{w: w, tmpl: tmpl}, nil
+}
+
+func (s *templateStream) Begin() error {
+	return nil
+}
+
+func (s *templateStream) Write(info *IPInfo) error {
+	var buf bytes.Buffer
+	if err := s.tmpl.Execute(&buf, info); err != nil {
+		return err
+	}
+	if buf.Len() == 0 || buf.Bytes()[buf.Len()-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	_, err := s.w.Write(buf.Bytes())
+	return err
+}
+
+func (s *templateStream) End() error {
+	return nil
+}
+
+// defaultValue returns def when v is empty, counting empty slices and maps:
+// {{.City | default "unknown"}}
+func defaultValue(def, v interface{}) interface{} {
+	if v == nil {
+		return def
+	}
+	rv := reflect.ValueOf(v)
+	if rv.IsZero() {
+		return def
+	}
+	if k := rv.Kind(); (k == reflect.Slice || k == reflect.Map) && rv.Len() == 0 {
+		return def
+	}
+	return v
+}
+
+// join joins the elements of a slice: {{.Tags | join ", "}}
+func join(sep string, v interface{}) string {
+	if s, ok := v.([]string); ok {
+		return strings.Join(s, sep)
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return fmt.Sprint(v)
+	}
+	parts := make([]string, rv.Len())
+	for i := range parts {
+		parts[i] = fmt.Sprint(rv.Index(i).Interface())
+	}
+	return strings.Join(parts, sep)
+}
+
+// countryFlag turns a two-letter country code into its flag emoji:
+// {{flag .CountryCode}}
+func countryFlag(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 2 || code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+		return ""
+	}
+	const regionalIndicatorA = 0x1F1E6
+	return string([]rune{regionalIndicatorA + rune(code[0]-'A'), regionalIndicatorA + rune(code[1]-'A')})
+}
+
+// truncate shortens s to n characters with an ellipsis:
+// {{.ISP | truncate 20}}
+func truncate(n int, s string) string {
+	if n < 1 {
+		return ""
+	}
+	return util.Truncate(s, n)
+}
@@ -1,34 +1,80 @@
 package formatter
 
 import (
+    "bytes"
     "fmt"
+    "io"
     "strings"
 )
 
+func init() {
+    Register(&outputFormat{
+        name:       "text",
+        mime:       "text/plain",
+        extensions: []string{"txt", "text"},
+        streaming:  true,
+        selects:    true,
+        newWriter: func(w io.Writer, opts Options) (StreamWriter, error) {
+            return newTextStream(w, opts.Fields)
+        },
+    })
+}
+
 // FormatText converts IPInfo slice into human-readable text with optional field filtering
 func FormatText(data []*IPInfo, fieldsStr string) (string, error) {
+    var buf bytes.Buffer
+    sw, err := newTextStream(&buf, fieldsStr)
+    if err != nil {
+        return "", err
+    }
+    return formatAll(sw, &buf, data)
+}
+
+// textStream writes one block of "Field: value" lines per record
+type textStream struct {
+    w      io.Writer
+    fields []string
+    count  int
+}
+
+func newTextStream(w io.Writer, fieldsStr string) (*textStream, error) {
     fields := parseFields(fieldsStr)
     if !validFields(fields) {
-        return "", fmt.Errorf("invalid field(s) specified for text format")
+        return nil, fmt.Errorf("invalid field(s) specified for text format")
     }
+    return &textStream{w: w, fields: fields}, nil
+}
 
-    var b strings.Builder
-
-    for i, info := range data {
-        ipMap := info.ToMap()
+func (s *textStream) Begin() error {
+    return nil
+}
 
-        if len(fields) == 0 {
-            fields = getAllFields()
-        }
+func (s *textStream) Write(info *IPInfo) error {
+    ipMap := info.ToMap()
 
-        for _, f := range fields {
-            b.WriteString(fmt.Sprintf("%s: %v\n", titleCase(f), ipMap[f]))
-        }
+    fields := s.fields
+    if len(fields) == 0 {
+        fields = getAllFields()
+    }
+    fields = expandFields(fields, info)
 
-        if i < len(data)-1 {
-            b.WriteString("\n---\n\n")
+    var b strings.Builder
+    if s.count > 0 {
+        b.WriteString("\n---\n\n")
+    }
+    for _, f := range fields {
+        if v, ok := ipMap[f]; ok {
+            b.WriteString(fmt.Sprintf("%s: %s\n", titleCase(f), fieldText(v)))
+        } else {
+            b.WriteString(fmt.Sprintf("%s: \n", titleCase(f)))
         }
     }
+    s.count++
+
+    _, err := io.WriteString(s.w, b.String())
+    return err
+}
 
-    return b.String(), nil
-}
\ No newline at end of file
+func (s *textStream) End() error {
+    return nil
+}
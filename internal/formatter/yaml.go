@@ -1,38 +1,71 @@
 package formatter
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 
 	"gopkg.in/yaml.v2"
 )
 
+func init() {
+	Register(&outputFormat{
+		name:       "yaml",
+		mime:       "application/yaml",
+		extensions: []string{"yaml", "yml"},
+		streaming:  true,
+		selects:    true,
+		appendable: true,
+		newWriter: func(w io.Writer, opts Options) (StreamWriter, error) {
+			return newYAMLStream(w, opts.Fields)
+		},
+	})
+}
+
 // FormatYAML converts IPInfo slice into YAML format with optional field filtering
 func FormatYAML(data []*IPInfo, fieldsStr string) (string, error) {
-	fields := parseFields(fieldsStr)
-	if !validFields(fields) {
-		return "", fmt.Errorf("invalid field(s) specified for YAML")
+	var buf bytes.Buffer
+	sw, err := newYAMLStream(&buf, fieldsStr)
+	if err != nil {
+		return "", err
 	}
+	return formatAll(sw, &buf, data)
+}
 
-	var result []map[string]interface{}
-
-	for _, info := range data {
-		raw := info.ToMap()
+// yamlStream writes a YAML sequence one item at a time; consecutive
+// single-item sequences concatenate into one valid sequence
+type yamlStream struct {
+	w      io.Writer
+	fields []string
+	count  int
+}
 
-		if len(fields) > 0 {
-			filtered := make(map[string]interface{})
-			for _, f := range fields {
-				filtered[f] = raw[f]
-			}
-			result = append(result, filtered)
-		} else {
-			result = append(result, raw)
-		}
+func newYAMLStream(w io.Writer, fieldsStr string) (*yamlStream, error) {
+	fields := parseFields(fieldsStr)
+	if !validFields(fields) {
+		return nil, fmt.Errorf("invalid field(s) specified for YAML")
 	}
+	return &yamlStream{w: w, fields: fields}, nil
+}
+
+func (s *yamlStream) Begin() error {
+	return nil
+}
 
-	yamlData, err := yaml.Marshal(result)
+func (s *yamlStream) Write(info *IPInfo) error {
+	yamlData, err := yaml.Marshal([]map[string]interface{}{selectFields(info, s.fields)})
 	if err != nil {
-		return "", err
+		return err
 	}
+	s.count++
+	_, err = s.w.Write(yamlData)
+	return err
+}
 
-	return string(yamlData), nil
+func (s *yamlStream) End() error {
+	if s.count > 0 {
+		return nil
+	}
+	_, err := io.WriteString(s.w, "[]\n")
+	return err
 }
@@ -0,0 +1,124 @@
+// Package iana classifies addresses using the IANA IPv4 and IPv6
+// Special-Purpose Address Registries, so addresses that are not globally
+// reachable can be recognised without asking a provider.
+package iana
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// Address types reported as address_type
+const (
+	TypeGlobal        = "global"
+	TypePrivate       = "private"
+	TypeLoopback      = "loopback"
+	TypeLinkLocal     = "link-local"
+	TypeCGNAT         = "cgnat"
+	TypeDocumentation = "documentation"
+	TypeMulticast     = "multicast"
+	TypeReserved      = "reserved"
+)
+
+// Block is one entry of the special-purpose registry
+type Block struct {
+	Prefix netip.Prefix
+	Name   string
+	RFC    string
+	Type   string
+
+	// Global is the registry's "Globally Reachable" column; only global
+	// addresses are worth looking up
+	Global bool
+}
+
+//go:embed special.csv
+var specialCSV string
+
+// blocks is the parsed registry, most specific prefix first
+var blocks = mustParse(specialCSV)
+
+// globalBlock is returned for addresses outside every special-purpose block
+var globalBlock = Block{Name: "Global Unicast", Type: TypeGlobal, Global: true}
+
+// Classify returns the most specific registry block containing addr, or a
+// global block when no special-purpose range applies. IPv4-mapped IPv6
+// addresses are classified by their IPv4 address.
+func Classify(addr netip.Addr) Block {
+	addr = addr.Unmap().WithZone("")
+	for _, b := range blocks {
+		if b.Prefix.Contains(addr) {
+			return b
+		}
+	}
+	return globalBlock
+}
+
+// Lookup classifies an address string; ok is false if it does not parse
+func Lookup(ip string) (Block, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return Block{}, false
+	}
+	return Classify(addr), true
+}
+
+// Blocks returns a copy of the registry, most specific prefix first
+func Blocks() []Block {
+	return append([]Block(nil), blocks...)
+}
+
+// String describes the block as "Name (RFC)"
+func (b Block) String() string {
+	if b.RFC == "" {
+		return b.Name
+	}
+	return fmt.Sprintf("%s (%s)", b.Name, b.RFC)
+}
+
+// mustParse reads the embedded table; it panics on a malformed row since the
+// table ships with the binary
+func mustParse(data string) []Block {
+	var lines []string
+	for _, line := range strings.Split(data, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+
+	r := csv.NewReader(strings.NewReader(strings.Join(lines, "\n")))
+	r.FieldsPerRecord = 5
+	var out []Block
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			panic(fmt.Sprintf("iana: bad special-purpose table: %v", err))
+		}
+		prefix, err := netip.ParsePrefix(rec[0])
+		if err != nil {
+			panic(fmt.Sprintf("iana: bad prefix %q: %v", rec[0], err))
+		}
+		out = append(out, Block{
+			Prefix: prefix.Masked(),
+			Name:   rec[1],
+			RFC:    rec[2],
+			Type:   rec[3],
+			Global: rec[4] == "true",
+		})
+	}
+
+	// Longest prefix first so the first match is the most specific; IPv4
+	// and IPv6 prefixes never contain each other
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Prefix.Bits() > out[j].Prefix.Bits()
+	})
+	return out
+}
@@ -0,0 +1,214 @@
+package input
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/util"
+)
+
+// DefaultMaxExpand is the largest number of addresses a single CIDR or range
+// may expand to unless -max-expand says otherwise (a /16)
+const DefaultMaxExpand = 65536
+
+// Sample block sizes: one address is looked up per /24 (IPv4) or /48 (IPv6)
+const (
+	SampleBitsIPv4 = 24
+	SampleBitsIPv6 = 48
+)
+
+// Options controls how CIDR blocks and ranges are expanded
+type Options struct {
+	// MaxExpand caps the addresses one entry may produce; 0 means no limit
+	MaxExpand uint64
+
+	// Sample yields one address per /24 (IPv4) or /48 (IPv6) block instead
+	// of every address, which is enough to geolocate whole allocations
+	Sample bool
+
+	// Unmap turns IPv4-mapped IPv6 addresses (::ffff:a.b.c.d) into IPv4
+	Unmap bool
+}
+
+// TooLargeError reports an entry that would expand past Options.MaxExpand
+type TooLargeError struct {
+	Entry string
+	Size  *big.Int
+	Limit uint64
+}
+
+func (e *TooLargeError) Error() string {
+	return fmt.Sprintf("%s expands to %s addresses, more than the limit of %d", e.Entry, e.Size, e.Limit)
+}
+
+// Range is an inclusive span of addresses of one family
+type Range struct {
+	First netip.Addr
+	Last  netip.Addr
+}
+
+// IsRange reports whether s looks like a CIDR block or an address range
+// rather than a single address
+func IsRange(s string) bool {
+	return strings.ContainsAny(s, "/-")
+}
+
+// ParseRange parses a CIDR block ("10.0.0.0/24") or an inclusive range
+// ("192.0.2.10-192.0.2.50"). Host bits in a CIDR are ignored; addresses are
+// read as util.ParseIP does.
+func ParseRange(s string) (Range, error) {
+	s = strings.TrimSpace(s)
+
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		addr, err := util.ParseIP(s[:i], false)
+		bits, ok := prefixBits(s[i+1:])
+		if err != nil || !ok || bits > addr.BitLen() {
+			return Range{}, fmt.Errorf("invalid CIDR %q", s)
+		}
+		prefix := netip.PrefixFrom(addr, bits).Masked()
+		return Range{First: prefix.Addr(), Last: lastAddr(prefix)}, nil
+	}
+
+	parts := strings.SplitN(s, "-", 2)
+	if len(parts) != 2 {
+		return Range{}, fmt.Errorf("invalid range %q", s)
+	}
+	first, err1 := util.ParseIP(parts[0], false)
+	last, err2 := util.ParseIP(parts[1], false)
+	if err1 != nil || err2 != nil {
+		return Range{}, fmt.Errorf("invalid range %q", s)
+	}
+	first, last = first.Unmap(), last.Unmap()
+	if first.Is4() != last.Is4() {
+		return Range{}, fmt.Errorf("invalid range %q: mixes IPv4 and IPv6", s)
+	}
+	if last.Less(first) {
+		return Range{}, fmt.Errorf("invalid range %q: end is before start", s)
+	}
+	return Range{First: first, Last: last}, nil
+}
+
+// prefixBits parses the length of a CIDR prefix
+func prefixBits(s string) (int, bool) {
+	if s == "" || len(s) > 3 || strings.Trim(s, "0123456789") != "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
+
+// Size returns the number of addresses in the range
+func (r Range) Size() *big.Int {
+	n := new(big.Int).Sub(addrInt(r.Last), addrInt(r.First))
+	return n.Add(n, big.NewInt(1))
+}
+
+// Each calls fn for every address in order until fn returns false
+func (r Range) Each(fn func(netip.Addr) bool) {
+	for a := r.First; a.IsValid(); a = a.Next() {
+		if !fn(a) || a == r.Last {
+			return
+		}
+	}
+}
+
+// Overlaps reports whether any address of the range is in p
+func (r Range) Overlaps(p netip.Prefix) bool {
+	p = p.Masked()
+	return !lastAddr(p).Less(r.First) && !r.Last.Less(p.Addr())
+}
+
+// sampleBits returns the block size used by Sample for the range's family
+func (r Range) sampleBits() int {
+	if r.First.Is4() {
+		return SampleBitsIPv4
+	}
+	return SampleBitsIPv6
+}
+
+// Blocks returns how many /sampleBits blocks the range touches
+func (r Range) Blocks() *big.Int {
+	bits := r.sampleBits()
+	shift := uint(r.First.BitLen() - bits)
+	first := new(big.Int).Rsh(addrInt(r.First), shift)
+	last := new(big.Int).Rsh(addrInt(r.Last), shift)
+	n := last.Sub(last, first)
+	return n.Add(n, big.NewInt(1))
+}
+
+// EachSample calls fn with one address from every /24 (IPv4) or /48 (IPv6)
+// block the range touches, preferring the block's first host address over
+// its network address
+func (r Range) EachSample(fn func(netip.Addr) bool) {
+	bits := r.sampleBits()
+	for start := r.First; start.IsValid() && !r.Last.Less(start); {
+		block := netip.PrefixFrom(start, bits).Masked()
+		end := lastAddr(block)
+		if r.Last.Less(end) {
+			end = r.Last
+		}
+
+		pick := start
+		if start == block.Addr() && start.Next().IsValid() && !end.Less(start.Next()) {
+			pick = start.Next()
+		}
+		if !fn(pick) || end == r.Last {
+			return
+		}
+		start = end.Next()
+	}
+}
+
+// Expand passes every address of entry (a single IP, CIDR block or range)
+// to fn in canonical form (util.CanonicalIP), stopping early if fn returns
+// false. Entries larger than opts.MaxExpand are rejected with a
+// *TooLargeError before anything is yielded.
+func Expand(entry string, opts Options, fn func(ip string) bool) error {
+	entry = strings.TrimSpace(entry)
+	if ip, err := util.CanonicalIP(entry, opts.Unmap); err == nil {
+		fn(ip)
+		return nil
+	}
+	if !IsRange(entry) {
+		return fmt.Errorf("invalid IP address, CIDR or range: %s", entry)
+	}
+
+	r, err := ParseRange(entry)
+	if err != nil {
+		return err
+	}
+
+	size := r.Size()
+	if opts.Sample {
+		size = r.Blocks()
+	}
+	if opts.MaxExpand > 0 && size.Cmp(new(big.Int).SetUint64(opts.MaxExpand)) > 0 {
+		return &TooLargeError{Entry: entry, Size: size, Limit: opts.MaxExpand}
+	}
+
+	yield := func(a netip.Addr) bool { return fn(a.String()) }
+	if opts.Sample {
+		r.EachSample(yield)
+	} else {
+		r.Each(yield)
+	}
+	return nil
+}
+
+// lastAddr returns the highest address in a prefix
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Masked().Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << uint(7-i%8)
+	}
+	last, _ := netip.AddrFromSlice(b)
+	return last
+}
+
+// addrInt converts an address to an integer for size arithmetic
+func addrInt(a netip.Addr) *big.Int {
+	return new(big.Int).SetBytes(a.AsSlice())
+}
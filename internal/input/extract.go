@@ -0,0 +1,174 @@
+package input
+
+import (
+	"bufio"
+	"io"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// defangDot matches the usual ways of defanging a dot: [.] (.) {.} [dot] (dot)
+	defangDot = regexp.MustCompile(`(?i)\s*[\[({]\s*(?:\.|dot)\s*[\])}]\s*`)
+
+	// defangColon matches a defanged colon: [:] (:)
+	defangColon = regexp.MustCompile(`[\[(]:[\])]`)
+
+	// defangScheme matches defanged URL schemes such as hxxp, hXXps, fxp
+	defangScheme = regexp.MustCompile(`(?i)\b(h[xt]{2}p|fxp)(s?)(\[?:\]?//)`)
+
+	ipv4Pattern = regexp.MustCompile(`\d{1,3}(?:\.\d{1,3}){3}`)
+	ipv6Pattern = regexp.MustCompile(`[0-9A-Fa-f:.]*:[0-9A-Fa-f:.]*(?:%[0-9A-Za-z_.\-]+)?`)
+)
+
+// Refang undoes common IOC obfuscations so "hxxp://8.8.8[.]8" reads
+// "http://8.8.8.8" again
+func Refang(s string) string {
+	s = defangDot.ReplaceAllString(s, ".")
+	s = defangColon.ReplaceAllString(s, ":")
+	return defangScheme.ReplaceAllStringFunc(s, func(m string) string {
+		parts := defangScheme.FindStringSubmatch(m)
+		scheme := "http"
+		if strings.EqualFold(parts[1], "fxp") {
+			scheme = "ftp"
+		}
+		return scheme + parts[2] + "://"
+	})
+}
+
+// ExtractIPs returns the IPv4 and IPv6 addresses embedded in a line of free
+// text in the order they appear, after refanging it. Ports, brackets and
+// zone IDs are dropped and IPv6 addresses are returned in canonical form.
+// Dotted numbers that are longer than an address (version strings, OIDs)
+// are not mistaken for one.
+func ExtractIPs(line string) []string {
+	line = Refang(line)
+
+	var found []extracted
+	for _, span := range ipv6Pattern.FindAllStringIndex(line, -1) {
+		start, end := trimIPv6Candidate(line, span[0], span[1])
+		if addr, ok := parseIPv6Candidate(line[start:end]); ok {
+			found = append(found, extracted{start: start, end: end, ip: addr})
+		}
+	}
+
+	// IPv4 matches inside an IPv6 address (::ffff:192.0.2.1) belong to it
+	v6 := len(found)
+	for _, span := range ipv4Pattern.FindAllStringIndex(line, -1) {
+		start, end := span[0], span[1]
+		if !isIPv4Boundary(line, start, end) || within(found[:v6], start) {
+			continue
+		}
+		if addr, err := netip.ParseAddr(line[start:end]); err == nil {
+			found = append(found, extracted{start: start, end: end, ip: addr.String()})
+		}
+	}
+
+	if len(found) == 0 {
+		return nil
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].start < found[j].start })
+	ips := make([]string, len(found))
+	for i, f := range found {
+		ips[i] = f.ip
+	}
+	return ips
+}
+
+// extracted is an address found in a line and where it was written
+type extracted struct {
+	start, end int
+	ip         string
+}
+
+// Extract scans r line by line and calls fn with every address found and
+// the 1-based line it was first seen on, until fn returns false. With dedupe
+// each address is reported only once.
+func Extract(r io.Reader, dedupe bool, fn func(ip string, line int) bool) error {
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	for n := 1; scanner.Scan(); n++ {
+		for _, ip := range ExtractIPs(scanner.Text()) {
+			if dedupe {
+				if seen[ip] {
+					continue
+				}
+				seen[ip] = true
+			}
+			if !fn(ip, n) {
+				return nil
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// trimIPv6Candidate drops what surrounds an IPv6-looking token, such as a
+// "Source:" label whose last letters happen to be hex digits or a
+// sentence-ending period
+func trimIPv6Candidate(s string, start, end int) (int, int) {
+	if start > 0 && isAlnum(s[start-1]) {
+		if i := strings.IndexByte(s[start:end], ':'); i >= 0 {
+			start += i
+		}
+	}
+	for start < end && s[start] == ':' && !strings.HasPrefix(s[start:end], "::") {
+		start++
+	}
+	for end > start && (s[end-1] == '.' || (s[end-1] == ':' && !strings.HasSuffix(s[start:end], "::"))) {
+		end--
+	}
+	return start, end
+}
+
+// parseIPv6Candidate validates a token as an IPv6 address, ignoring any zone
+func parseIPv6Candidate(token string) (string, bool) {
+	if i := strings.IndexByte(token, '%'); i >= 0 {
+		token = token[:i]
+	}
+	if !strings.ContainsAny(token, "0123456789abcdefABCDEF") {
+		return "", false
+	}
+	addr, err := netip.ParseAddr(token)
+	if err != nil || !addr.Is6() {
+		return "", false
+	}
+	return addr.String(), true
+}
+
+// isIPv4Boundary reports whether s[start:end] stands alone rather than being
+// part of a longer dotted number
+func isIPv4Boundary(s string, start, end int) bool {
+	if start > 0 {
+		if c := s[start-1]; isDigit(c) || (c == '.' && start > 1 && isDigit(s[start-2])) {
+			return false
+		}
+	}
+	if end < len(s) {
+		if c := s[end]; isDigit(c) || (c == '.' && end+1 < len(s) && isDigit(s[end+1])) {
+			return false
+		}
+	}
+	return true
+}
+
+// within reports whether pos falls inside one of the found addresses
+func within(found []extracted, pos int) bool {
+	for _, f := range found {
+		if pos >= f.start && pos < f.end {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlnum(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,74 @@
+package input
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"github.com/ODIN7h3C0d3r/Netra/internal/util"
+)
+
+// Target is one address to look up and, when it came from resolving a
+// hostname, URL or email address, the input it was resolved from. Line is
+// where an extracted address was found and Columns the structured input row
+// it came from.
+type Target struct {
+	IP      string
+	Source  string
+	Line    int
+	Columns []formatter.Column
+
+	// Original is the address as written in the input when it differs
+	// from the canonical IP ("008.008.008.008", "2001:DB8::1")
+	Original string
+}
+
+// Hostname extracts the host to resolve from a hostname ("example.com",
+// "example.com:8443"), URL ("https://example.com/path") or email address
+// ("user@example.com"). It reports false for IPs, CIDR blocks, ranges and
+// anything else that does not name a host. The host of a URL may itself be
+// an IP address.
+func Hostname(entry string) (string, bool) {
+	entry = strings.TrimSpace(entry)
+	if entry == "" || util.IsValidIP(entry) {
+		return "", false
+	}
+
+	var host string
+	switch {
+	case strings.Contains(entry, "://"):
+		u, err := url.Parse(entry)
+		if err != nil || u.Hostname() == "" {
+			return "", false
+		}
+		host = u.Hostname()
+		if util.IsValidIP(host) {
+			return host, true
+		}
+	case strings.Contains(entry, "@"):
+		host = entry[strings.LastIndex(entry, "@")+1:]
+	default:
+		host = entry
+		if h, _, err := net.SplitHostPort(entry); err == nil {
+			host = h
+		}
+	}
+
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if !util.IsValidHostname(host) || !hasLetter(host[strings.LastIndex(host, ".")+1:]) {
+		return "", false
+	}
+	return host, true
+}
+
+// hasLetter reports whether s contains an ASCII letter; a real top-level
+// domain always does, which keeps malformed IPs from passing as hostnames
+func hasLetter(s string) bool {
+	for _, r := range s {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+			return true
+		}
+	}
+	return false
+}
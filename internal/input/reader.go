@@ -0,0 +1,54 @@
+package input
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+)
+
+// Entry is one line of input, or with extraction one address found in the
+// input, and the 1-based line it came from (0 when not tracked). Entries
+// read from CSV or JSON rows carry the whole row in Columns.
+type Entry struct {
+	Text    string
+	Line    int
+	Columns []formatter.Column
+}
+
+// maxLineLength bounds a single input line; longer lines fail the read
+const maxLineLength = 1024 * 1024
+
+// StripComment removes a "#" comment from an input line and trims it. A
+// comment starts at a leading "#" or at a "#" preceded by whitespace, so
+// URL fragments ("https://example.com/#top") are kept.
+func StripComment(line string) string {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "#") {
+		return ""
+	}
+	if i := strings.Index(line, " #"); i >= 0 {
+		line = line[:i]
+	}
+	if i := strings.Index(line, "\t#"); i >= 0 {
+		line = line[:i]
+	}
+	return strings.TrimSpace(line)
+}
+
+// ReadEntries reads r line by line and passes every non-empty entry, with
+// comments removed, to fn until fn returns false. Nothing beyond the current
+// line is held in memory, so it works on unbounded streams such as stdin.
+func ReadEntries(r io.Reader, fn func(entry string) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	for scanner.Scan() {
+		if entry := StripComment(scanner.Text()); entry != "" {
+			if !fn(entry) {
+				return nil
+			}
+		}
+	}
+	return scanner.Err()
+}
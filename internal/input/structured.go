@@ -0,0 +1,166 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"github.com/ODIN7h3C0d3r/Netra/internal/util"
+)
+
+// InputFormats lists the -input-format values; "lines" is one entry per line
+var InputFormats = []string{"lines", "csv", "jsonl"}
+
+// DefaultIPColumns are the column names (CSV) or keys (JSON) searched for
+// the address when no column is given
+var DefaultIPColumns = []string{"ip", "src_ip", "source_ip", "client_ip", "remote_addr", "ip_address", "address", "dst_ip", "host"}
+
+// ReadCSV reads a CSV file with a header row and passes one entry per row,
+// taking the entry from column (a header name or 1-based number) and keeping
+// every cell as a passthrough column. Rows are streamed until fn returns false.
+func ReadCSV(r io.Reader, column string, fn func(Entry) bool) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read CSV header: %v", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	idx, err := csvColumn(header, column)
+	if err != nil {
+		return err
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		columns := make([]formatter.Column, len(header))
+		for i, name := range header {
+			var value string
+			if i < len(row) {
+				value = row[i]
+			}
+			columns[i] = formatter.Column{Name: name, Value: value}
+		}
+
+		var text string
+		if idx < len(row) {
+			text = strings.TrimSpace(row[idx])
+		}
+		if !fn(Entry{Text: text, Columns: columns}) {
+			return nil
+		}
+	}
+}
+
+// csvColumn finds the address column in a CSV header
+func csvColumn(header []string, column string) (int, error) {
+	if column == "" {
+		for _, name := range DefaultIPColumns {
+			for i, h := range header {
+				if strings.EqualFold(strings.TrimSpace(h), name) {
+					return i, nil
+				}
+			}
+		}
+		return 0, fmt.Errorf("no IP column found in CSV header %v; use -ip-column", header)
+	}
+
+	for i, h := range header {
+		if strings.EqualFold(strings.TrimSpace(h), column) {
+			return i, nil
+		}
+	}
+	if n, err := strconv.Atoi(column); err == nil && n >= 1 && n <= len(header) {
+		return n - 1, nil
+	}
+	return 0, fmt.Errorf("column %q not in CSV header %v", column, header)
+}
+
+// ReadJSONL reads one JSON object per line and passes one entry per object,
+// taking the entry from path (a key, dotted path or JSONPath such as
+// "$.source.ip") and keeping the object's top-level keys, in order, as
+// passthrough columns. Lines are streamed until fn returns false.
+func ReadJSONL(r io.Reader, path string, fn func(Entry) bool) error {
+	paths := DefaultIPColumns
+	if path != "" {
+		paths = []string{util.NormalizeJSONPath(path)}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		columns, err := decodeObject(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", n, err)
+		}
+		doc := make(map[string]interface{}, len(columns))
+		for _, c := range columns {
+			doc[c.Name] = c.Value
+		}
+
+		var text string
+		for _, p := range paths {
+			if v, ok := util.LookupPath(doc, p); ok && v != nil {
+				text = strings.TrimSpace(fmt.Sprintf("%v", v))
+				break
+			}
+		}
+		if !fn(Entry{Text: text, Columns: columns}) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// decodeObject decodes a JSON object into its members in document order
+func decodeObject(data []byte) ([]formatter.Column, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errors.New("not a JSON object")
+	}
+
+	var columns []formatter.Column
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		columns = append(columns, formatter.Column{Name: tok.(string), Value: value})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
@@ -0,0 +1,71 @@
+package logs
+
+import (
+	"sort"
+	"time"
+)
+
+// Aggregate summarizes every record from one client address
+type Aggregate struct {
+	IP        string
+	Requests  int
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Paths     map[string]int
+	Users     map[string]int
+}
+
+// Aggregator groups records by client address
+type Aggregator struct {
+	byIP map[string]*Aggregate
+}
+
+// NewAggregator returns an empty aggregator
+func NewAggregator() *Aggregator {
+	return &Aggregator{byIP: make(map[string]*Aggregate)}
+}
+
+// Add counts one record
+func (a *Aggregator) Add(rec Record) {
+	agg, ok := a.byIP[rec.IP]
+	if !ok {
+		agg = &Aggregate{IP: rec.IP, Paths: make(map[string]int), Users: make(map[string]int)}
+		a.byIP[rec.IP] = agg
+	}
+
+	agg.Requests++
+	if !rec.Time.IsZero() {
+		if agg.FirstSeen.IsZero() || rec.Time.Before(agg.FirstSeen) {
+			agg.FirstSeen = rec.Time
+		}
+		if rec.Time.After(agg.LastSeen) {
+			agg.LastSeen = rec.Time
+		}
+	}
+	if rec.Path != "" {
+		agg.Paths[rec.Path]++
+	}
+	if rec.User != "" {
+		agg.Users[rec.User]++
+	}
+}
+
+// Len returns the number of distinct client addresses
+func (a *Aggregator) Len() int {
+	return len(a.byIP)
+}
+
+// Results returns the aggregates, busiest client first
+func (a *Aggregator) Results() []*Aggregate {
+	out := make([]*Aggregate, 0, len(a.byIP))
+	for _, agg := range a.byIP {
+		out = append(out, agg)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Requests != out[j].Requests {
+			return out[i].Requests > out[j].Requests
+		}
+		return out[i].IP < out[j].IP
+	})
+	return out
+}
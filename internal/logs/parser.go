@@ -0,0 +1,275 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/util"
+)
+
+// Record is what a parser takes from one log line. Time, Path and User are
+// empty when the format or line does not carry them.
+type Record struct {
+	IP   string
+	Time time.Time
+	Path string
+	User string
+}
+
+// Parser turns one log line into a Record; ok is false for lines that are
+// not records of the format (or carry no client address)
+type Parser interface {
+	Parse(line string) (rec Record, ok bool)
+}
+
+// Options tunes the parsers
+type Options struct {
+	// JSONPath selects the client address in JSON-lines logs, e.g.
+	// "$.request.remote_ip"; when empty common field names are tried
+	JSONPath string
+
+	// Now anchors syslog timestamps, which have no year; defaults to time.Now
+	Now time.Time
+}
+
+// Formats lists the supported log formats
+var Formats = []string{"nginx-combined", "apache", "sshd", "jsonl"}
+
+// NewParser returns the parser for a log format
+func NewParser(format string, opts Options) (Parser, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	switch strings.ToLower(format) {
+	case "nginx-combined", "nginx", "apache", "apache-combined", "apache-common", "combined", "common":
+		return accessLogParser{}, nil
+	case "sshd", "auth", "auth.log":
+		return sshdParser{now: opts.Now}, nil
+	case "jsonl", "json":
+		return newJSONParser(opts.JSONPath), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q (available: %s)", format, strings.Join(Formats, ", "))
+}
+
+// accessLogParser reads the NCSA common and combined formats written by
+// nginx and Apache:
+//
+//	203.0.113.9 - alice [18/Oct/2026:10:22:01 +0000] "GET /login?next=/ HTTP/1.1" 200 512 "-" "curl/8.0"
+type accessLogParser struct{}
+
+var accessLogLine = regexp.MustCompile(`^(\S+) \S+ (\S+) \[([^\]]+)\] "([^"]*)"`)
+
+const accessLogTime = "02/Jan/2006:15:04:05 -0700"
+
+func (accessLogParser) Parse(line string) (Record, bool) {
+	m := accessLogLine.FindStringSubmatch(line)
+	if m == nil {
+		return Record{}, false
+	}
+	ip, ok := clientIP(m[1])
+	if !ok {
+		return Record{}, false
+	}
+
+	rec := Record{IP: ip, Path: requestPath(m[4])}
+	if m[2] != "-" {
+		rec.User = m[2]
+	}
+	if t, err := time.Parse(accessLogTime, m[3]); err == nil {
+		rec.Time = t
+	}
+	return rec, true
+}
+
+// requestPath takes the path from a request line ("GET /a?b=c HTTP/1.1"),
+// without the query string so hits on one endpoint aggregate together
+func requestPath(request string) string {
+	parts := strings.Fields(request)
+	if len(parts) < 2 {
+		return ""
+	}
+	return stripQuery(parts[1])
+}
+
+func stripQuery(path string) string {
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
+
+// sshdParser reads OpenSSH messages from syslog (auth.log, secure) or
+// journalctl output, keeping those that name a client:
+//
+//	Oct 18 10:22:01 web1 sshd[812]: Failed password for invalid user admin from 203.0.113.9 port 52311 ssh2
+type sshdParser struct {
+	now time.Time
+}
+
+var (
+	sshdFrom = regexp.MustCompile(`\bfrom (\S+) port \d+`)
+	sshdUser = regexp.MustCompile(`\b(?:for (?:invalid user )?|[Ii]nvalid user )(\S+) from `)
+)
+
+const syslogTime = "Jan _2 15:04:05"
+
+func (p sshdParser) Parse(line string) (Record, bool) {
+	if !strings.Contains(line, "sshd[") && !strings.Contains(line, "sshd:") {
+		return Record{}, false
+	}
+	m := sshdFrom.FindStringSubmatch(line)
+	if m == nil {
+		return Record{}, false
+	}
+	ip, ok := clientIP(m[1])
+	if !ok {
+		return Record{}, false
+	}
+
+	rec := Record{IP: ip, Time: p.timestamp(line)}
+	if u := sshdUser.FindStringSubmatch(line); u != nil {
+		rec.User = u[1]
+	}
+	return rec, true
+}
+
+// timestamp reads an RFC 3339 or classic syslog timestamp at the start of
+// the line. Syslog omits the year, so the most recent matching date not in
+// the future is used.
+func (p sshdParser) timestamp(line string) time.Time {
+	if i := strings.IndexByte(line, ' '); i > 0 {
+		if t, err := time.Parse(time.RFC3339Nano, line[:i]); err == nil {
+			return t
+		}
+	}
+	if len(line) < len(syslogTime) {
+		return time.Time{}
+	}
+	t, err := time.ParseInLocation(syslogTime, line[:len(syslogTime)], p.now.Location())
+	if err != nil {
+		return time.Time{}
+	}
+	t = t.AddDate(p.now.Year(), 0, 0)
+	if t.After(p.now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t
+}
+
+// jsonParser reads one JSON object per line, as written by nginx
+// (escape=json), Caddy, Traefik and most application loggers
+type jsonParser struct {
+	ipPaths []string
+}
+
+// Field names tried when the log does not say where a value lives
+var (
+	jsonIPFields   = []string{"remote_addr", "client_ip", "clientip", "ip", "src_ip", "remote_ip", "request.remote_ip", "ClientHost"}
+	jsonTimeFields = []string{"time", "timestamp", "@timestamp", "ts", "time_local", "StartUTC"}
+	jsonPathFields = []string{"path", "uri", "request_uri", "request.uri", "url", "RequestPath"}
+	jsonUserFields = []string{"user", "username", "remote_user", "user_name", "request.user_id"}
+)
+
+func newJSONParser(path string) jsonParser {
+	if path == "" {
+		return jsonParser{ipPaths: jsonIPFields}
+	}
+	return jsonParser{ipPaths: []string{util.NormalizeJSONPath(path)}}
+}
+
+func (p jsonParser) Parse(line string) (Record, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "{") {
+		return Record{}, false
+	}
+	var doc interface{}
+	if err := json.Unmarshal([]byte(line), &doc); err != nil {
+		return Record{}, false
+	}
+
+	raw, ok := firstString(doc, p.ipPaths)
+	if !ok {
+		return Record{}, false
+	}
+	ip, ok := clientIP(raw)
+	if !ok {
+		return Record{}, false
+	}
+
+	rec := Record{IP: ip}
+	if path, ok := firstString(doc, jsonPathFields); ok {
+		rec.Path = stripQuery(path)
+	}
+	rec.User, _ = firstString(doc, jsonUserFields)
+	for _, path := range jsonTimeFields {
+		if v, ok := util.LookupPath(doc, path); ok {
+			if t, ok := parseTimeValue(v); ok {
+				rec.Time = t
+				break
+			}
+		}
+	}
+	return rec, true
+}
+
+// firstString returns the first of paths that holds a non-empty, non-"-" value
+func firstString(doc interface{}, paths []string) (string, bool) {
+	for _, path := range paths {
+		v, ok := util.LookupPath(doc, path)
+		if !ok || v == nil {
+			continue
+		}
+		s := strings.TrimSpace(fmt.Sprintf("%v", v))
+		if s != "" && s != "-" {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// parseTimeValue accepts RFC 3339 strings, access-log timestamps and Unix
+// times in seconds or milliseconds
+func parseTimeValue(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case float64:
+		return unixTime(t), true
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, accessLogTime} {
+			if parsed, err := time.Parse(layout, t); err == nil {
+				return parsed, true
+			}
+		}
+		if f, err := strconv.ParseFloat(t, 64); err == nil {
+			return unixTime(f), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func unixTime(f float64) time.Time {
+	if f > 1e12 {
+		f /= 1000
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9)).UTC()
+}
+
+// clientIP validates a client address, dropping any port ("1.2.3.4:5678",
+// "[2001:db8::1]:443") and zone
+func clientIP(s string) (string, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(strings.Trim(s, "[]"))
+	if err != nil {
+		return "", false
+	}
+	return addr.WithZone("").String(), true
+}
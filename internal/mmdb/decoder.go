@@ -0,0 +1,218 @@
+package mmdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Data types defined by the MaxMind DB format specification
+const (
+	typeExtended  = 0
+	typePointer   = 1
+	typeString    = 2
+	typeDouble    = 3
+	typeBytes     = 4
+	typeUint16    = 5
+	typeUint32    = 6
+	typeMap       = 7
+	typeInt32     = 8
+	typeUint64    = 9
+	typeUint128   = 10
+	typeArray     = 11
+	typeContainer = 12
+	typeEndMarker = 13
+	typeBool      = 14
+	typeFloat     = 15
+)
+
+// maxDepth guards against pointer loops in corrupt files
+const maxDepth = 64
+
+// decoder reads values from a data section; pointers are relative to base
+type decoder struct {
+	buf  []byte
+	base int
+}
+
+// decode returns the value at offset and the offset just past it.
+// Maps decode to map[string]interface{}, arrays to []interface{}, unsigned
+// integers to uint64 (uint128 to *big.Int), doubles and floats to float64.
+func (d *decoder) decode(offset int) (interface{}, int, error) {
+	return d.decodeDepth(offset, 0)
+}
+
+func (d *decoder) decodeDepth(offset, depth int) (interface{}, int, error) {
+	if depth > maxDepth {
+		return nil, 0, fmt.Errorf("mmdb: maximum data structure depth exceeded")
+	}
+
+	typ, size, offset, err := d.ctrl(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if typ == typePointer {
+		target, next, err := d.pointer(size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decodeDepth(target, depth+1)
+		return value, next, err
+	}
+
+	switch typ {
+	case typeMap:
+		m := make(map[string]interface{}, size)
+		for i := 0; i < size; i++ {
+			key, next, err := d.decodeDepth(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("mmdb: map key at offset %d is not a string", offset)
+			}
+			value, after, err := d.decodeDepth(next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			m[k] = value
+			offset = after
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]interface{}, 0, size)
+		for i := 0; i < size; i++ {
+			value, next, err := d.decodeDepth(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+			offset = next
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	case typeContainer, typeEndMarker:
+		return nil, offset, nil
+	}
+
+	end := offset + size
+	if end > len(d.buf) || end < offset {
+		return nil, 0, fmt.Errorf("mmdb: value at offset %d runs past end of data", offset)
+	}
+	raw := d.buf[offset:end]
+
+	switch typ {
+	case typeString:
+		return string(raw), end, nil
+	case typeBytes:
+		b := make([]byte, size)
+		copy(b, raw)
+		return b, end, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("mmdb: invalid double size %d", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), end, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("mmdb: invalid float size %d", size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(raw))), end, nil
+	case typeUint16, typeUint32, typeUint64:
+		if size > 8 {
+			return nil, 0, fmt.Errorf("mmdb: invalid integer size %d", size)
+		}
+		return uintFromBytes(raw), end, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, fmt.Errorf("mmdb: invalid int32 size %d", size)
+		}
+		return int64(int32(uintFromBytes(raw))), end, nil
+	case typeUint128:
+		if size > 16 {
+			return nil, 0, fmt.Errorf("mmdb: invalid uint128 size %d", size)
+		}
+		return new(big.Int).SetBytes(raw), end, nil
+	}
+
+	return nil, 0, fmt.Errorf("mmdb: unknown data type %d at offset %d", typ, offset)
+}
+
+// ctrl parses a control byte (plus extended type and size bytes) and returns
+// the type, the payload size and the offset of the payload
+func (d *decoder) ctrl(offset int) (int, int, int, error) {
+	if offset >= len(d.buf) {
+		return 0, 0, 0, fmt.Errorf("mmdb: offset %d past end of data", offset)
+	}
+	ctrl := d.buf[offset]
+	offset++
+
+	typ := int(ctrl >> 5)
+	if typ == typeExtended {
+		if offset >= len(d.buf) {
+			return 0, 0, 0, fmt.Errorf("mmdb: truncated extended type")
+		}
+		typ = 7 + int(d.buf[offset])
+		offset++
+	}
+
+	size := int(ctrl & 0x1f)
+	if typ == typePointer {
+		return typ, size, offset, nil
+	}
+
+	if size >= 29 {
+		n := size - 28
+		if offset+n > len(d.buf) {
+			return 0, 0, 0, fmt.Errorf("mmdb: truncated size bytes")
+		}
+		v := int(uintFromBytes(d.buf[offset : offset+n]))
+		offset += n
+		switch size {
+		case 29:
+			size = 29 + v
+		case 30:
+			size = 285 + v
+		default:
+			size = 65821 + v
+		}
+	}
+
+	return typ, size, offset, nil
+}
+
+// pointer decodes a pointer whose size bits are in sizeBits; it returns the
+// absolute target offset and the offset just past the pointer bytes
+func (d *decoder) pointer(sizeBits, offset int) (int, int, error) {
+	n := ((sizeBits >> 3) & 0x3) + 1
+	if offset+n > len(d.buf) {
+		return 0, 0, fmt.Errorf("mmdb: truncated pointer")
+	}
+	raw := d.buf[offset : offset+n]
+
+	var target int
+	switch n {
+	case 1:
+		target = (sizeBits&0x7)<<8 | int(raw[0])
+	case 2:
+		target = ((sizeBits&0x7)<<16 | int(raw[0])<<8 | int(raw[1])) + 2048
+	case 3:
+		target = ((sizeBits&0x7)<<24 | int(raw[0])<<16 | int(raw[1])<<8 | int(raw[2])) + 526336
+	default:
+		target = int(binary.BigEndian.Uint32(raw))
+	}
+
+	return d.base + target, offset + n, nil
+}
+
+func uintFromBytes(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
@@ -0,0 +1,258 @@
+package mmdb
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+)
+
+// metadataMarker precedes the metadata map at the end of every MMDB file
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// maxMetadataSize bounds how far from the end of the file the marker is searched
+const maxMetadataSize = 128 * 1024
+
+// maxCachedRecords bounds the decoded-record cache; databases share records
+// between many networks, so a small cache serves most batch lookups
+const maxCachedRecords = 1 << 16
+
+// Metadata describes a database file
+type Metadata struct {
+	DatabaseType string
+	Description  string
+	IPVersion    int
+	NodeCount    int
+	RecordSize   int
+	BuildEpoch   uint64
+	Languages    []string
+}
+
+// Reader looks up IP addresses in a MaxMind DB (.mmdb) file held in memory.
+// It is safe for concurrent use.
+type Reader struct {
+	Metadata Metadata
+
+	buf       []byte
+	data      decoder
+	treeSize  int
+	ipv4Start int
+
+	mu      sync.RWMutex
+	records map[int]map[string]interface{}
+}
+
+// Open reads a database file into memory
+func Open(path string) (*Reader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := FromBytes(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return r, nil
+}
+
+// FromBytes parses a database already in memory
+func FromBytes(buf []byte) (*Reader, error) {
+	start := len(buf) - maxMetadataSize
+	if start < 0 {
+		start = 0
+	}
+	idx := bytes.LastIndex(buf[start:], metadataMarker)
+	if idx < 0 {
+		return nil, fmt.Errorf("not a MaxMind DB file (metadata marker not found)")
+	}
+	metaStart := start + idx + len(metadataMarker)
+
+	metaDecoder := decoder{buf: buf, base: metaStart}
+	raw, _, err := metaDecoder.decode(metaStart)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata: %v", err)
+	}
+	meta, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid metadata: not a map")
+	}
+
+	md := Metadata{
+		DatabaseType: asString(meta["database_type"]),
+		IPVersion:    int(asUint(meta["ip_version"])),
+		NodeCount:    int(asUint(meta["node_count"])),
+		RecordSize:   int(asUint(meta["record_size"])),
+		BuildEpoch:   asUint(meta["build_epoch"]),
+	}
+	if desc, ok := meta["description"].(map[string]interface{}); ok {
+		md.Description = asString(desc["en"])
+	}
+	if langs, ok := meta["languages"].([]interface{}); ok {
+		for _, l := range langs {
+			md.Languages = append(md.Languages, asString(l))
+		}
+	}
+
+	switch md.RecordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("unsupported record size %d", md.RecordSize)
+	}
+	if md.IPVersion != 4 && md.IPVersion != 6 {
+		return nil, fmt.Errorf("unsupported ip_version %d", md.IPVersion)
+	}
+
+	treeSize := md.NodeCount * md.RecordSize / 4
+	if treeSize+16 > metaStart-len(metadataMarker) {
+		return nil, fmt.Errorf("search tree larger than file")
+	}
+
+	r := &Reader{
+		Metadata: md,
+		buf:      buf,
+		data:     decoder{buf: buf[:metaStart-len(metadataMarker)], base: treeSize + 16},
+		treeSize: treeSize,
+		records:  make(map[int]map[string]interface{}),
+	}
+
+	// IPv4 addresses live under ::/96 in IPv6 databases; find that node once
+	if md.IPVersion == 6 {
+		node := 0
+		for i := 0; i < 96 && node < md.NodeCount; i++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+
+	return r, nil
+}
+
+// Lookup returns the record for ip, or nil if the address is not in the
+// database; IPv6 addresses are always missing from an IPv4-only database
+func (r *Reader) Lookup(ip net.IP) (map[string]interface{}, error) {
+	offset, err := r.lookupOffset(ip)
+	if err != nil || offset < 0 {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	rec, ok := r.records[offset]
+	r.mu.RUnlock()
+	if ok {
+		return rec, nil
+	}
+
+	value, _, err := r.data.decode(offset)
+	if err != nil {
+		return nil, err
+	}
+	rec, ok = value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("mmdb: record at offset %d is not a map", offset)
+	}
+
+	r.mu.Lock()
+	if len(r.records) < maxCachedRecords {
+		r.records[offset] = rec
+	}
+	r.mu.Unlock()
+	return rec, nil
+}
+
+// lookupOffset walks the search tree and returns the absolute data offset, or -1
+func (r *Reader) lookupOffset(ip net.IP) (int, error) {
+	node := 0
+	addr := ip.To4()
+	bits := 32
+
+	if addr == nil {
+		if r.Metadata.IPVersion == 4 {
+			// An IPv4-only database has no IPv6 networks, so this is a miss
+			return -1, nil
+		}
+		addr = ip.To16()
+		if addr == nil {
+			return -1, fmt.Errorf("mmdb: invalid IP address")
+		}
+		bits = 128
+	} else if r.Metadata.IPVersion == 6 {
+		node = r.ipv4Start
+	}
+
+	nodeCount := r.Metadata.NodeCount
+	for i := 0; i < bits && node < nodeCount; i++ {
+		bit := int(addr[i>>3]>>(7-uint(i&7))) & 1
+		node = r.record(node, bit)
+	}
+
+	if node == nodeCount {
+		return -1, nil
+	}
+	if node < nodeCount {
+		return -1, fmt.Errorf("mmdb: invalid search tree")
+	}
+
+	offset := node - nodeCount + r.treeSize
+	if offset >= len(r.data.buf) {
+		return -1, fmt.Errorf("mmdb: invalid data pointer in search tree")
+	}
+	return offset, nil
+}
+
+// record returns the left (bit 0) or right (bit 1) record of a tree node
+func (r *Reader) record(node, bit int) int {
+	switch r.Metadata.RecordSize {
+	case 24:
+		b := r.buf[node*6+bit*3:]
+		return int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+	case 28:
+		b := r.buf[node*7:]
+		if bit == 0 {
+			return int(b[3]&0xF0)<<20 | int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+		}
+		return int(b[3]&0x0F)<<24 | int(b[4])<<16 | int(b[5])<<8 | int(b[6])
+	default:
+		b := r.buf[node*8+bit*4:]
+		return int(b[0])<<24 | int(b[1])<<16 | int(b[2])<<8 | int(b[3])
+	}
+}
+
+// Path walks nested maps and arrays, e.g. Path(rec, "country", "names", "en")
+func Path(rec interface{}, keys ...interface{}) interface{} {
+	cur := rec
+	for _, key := range keys {
+		switch k := key.(type) {
+		case string:
+			m, ok := cur.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			cur = m[k]
+		case int:
+			a, ok := cur.([]interface{})
+			if !ok || k < 0 || k >= len(a) {
+				return nil
+			}
+			cur = a[k]
+		default:
+			return nil
+		}
+	}
+	return cur
+}
+
+func asString(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func asUint(v interface{}) uint64 {
+	switch t := v.(type) {
+	case uint64:
+		return t
+	case int64:
+		return uint64(t)
+	}
+	return 0
+}
@@ -64,13 +64,18 @@ func NewCustomHTTPClient(cfg HTTPClientConfig) (*CustomHTTPClient, error) {
 	}, nil
 }
 
-// Do executes a request with retry logic and context
+// Do executes a request with retry logic. Retries and the waits between
+// them stop as soon as the request's context is done.
 func (c *CustomHTTPClient) Do(req *http.Request) (*http.Response, error) {
 	var resp *http.Response
 	var err error
+	ctx := req.Context()
 
 	for attempt := 0; attempt < c.cfg.RetryLimit; attempt++ {
-		req = req.Clone(context.Background())
+		req = req.Clone(ctx)
+		if c.cfg.UserAgent != "" && req.Header.Get("User-Agent") == "" {
+			req.Header.Set("User-Agent", c.cfg.UserAgent)
+		}
 		resp, err = c.client.Do(req)
 		if err == nil {
 			if resp.StatusCode < 500 || resp.StatusCode == 429 {
@@ -79,13 +84,31 @@ func (c *CustomHTTPClient) Do(req *http.Request) (*http.Response, error) {
 		}
 
 		if attempt < c.cfg.RetryLimit-1 {
-			time.Sleep(time.Second * time.Duration(attempt+1))
+			if resp != nil {
+				resp.Body.Close()
+			}
+			if sleepErr := SleepContext(ctx, time.Second*time.Duration(attempt+1)); sleepErr != nil {
+				return nil, sleepErr
+			}
 		}
 	}
 
 	return resp, err
 }
 
+// SleepContext waits for d or until ctx is done, returning ctx.Err() in that case
+func SleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 // ParseProxyURL parses and validates a proxy URL
 func ParseProxyURL(proxy string) (func(*http.Request) (*url.URL, error), error) {
 	proxyURL, err := url.Parse(proxy)
@@ -35,8 +35,8 @@ func NewDNSResolver(servers []string) *DNSResolver {
 }
 
 // ResolveHostnameToIP resolves a hostname to an IP address
-func (r *DNSResolver) ResolveHostnameToIP(hostname string) (string, error) {
-    ctx, cancel := context.WithTimeout(context.Background(), r.Timeout)
+func (r *DNSResolver) ResolveHostnameToIP(ctx context.Context, hostname string) (string, error) {
+    ctx, cancel := context.WithTimeout(ctx, r.Timeout)
     defer cancel()
 
     ips, err := r.Resolver.LookupIP(ctx, "ip", hostname)
@@ -52,8 +52,8 @@ func (r *DNSResolver) ResolveHostnameToIP(hostname string) (string, error) {
 }
 
 // ResolveIPToHostname resolves an IP address to a hostname
-func (r *DNSResolver) ResolveIPToHostname(ip string) (string, error) {
-    ctx, cancel := context.WithTimeout(context.Background(), r.Timeout)
+func (r *DNSResolver) ResolveIPToHostname(ctx context.Context, ip string) (string, error) {
+    ctx, cancel := context.WithTimeout(ctx, r.Timeout)
     defer cancel()
 
     names, err := r.Resolver.LookupAddr(ctx, ip)
@@ -69,8 +69,8 @@ func (r *DNSResolver) ResolveIPToHostname(ip string) (string, error) {
 }
 
 // GetAllIPs returns all IP addresses (IPv4 and IPv6) for a hostname
-func (r *DNSResolver) GetAllIPs(hostname string) ([]string, error) {
-    ctx, cancel := context.WithTimeout(context.Background(), r.Timeout)
+func (r *DNSResolver) GetAllIPs(ctx context.Context, hostname string) ([]string, error) {
+    ctx, cancel := context.WithTimeout(ctx, r.Timeout)
     defer cancel()
 
     ipv4, err4 := r.Resolver.LookupIP(ctx, "ip4", hostname)
@@ -1,29 +1,38 @@
 package network
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
-
-	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"strconv"
+	"time"
 )
 
-// FetchIPInfo fetches IP geolocation data from the specified API endpoint
-func FetchIPInfo(client *CustomHTTPClient, baseURL, ip string) (*formatter.IPInfo, error) {
-	url := strings.TrimSuffix(baseURL, "/") + "/"
-	if ip != "" {
-		url += ip + "/json/"
-	} else {
-		url += "json/"
+// RateLimitError is returned when an API answers 429 Too Many Requests
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limit exceeded. retry after: %s", e.RetryAfter)
 	}
+	return "rate limit exceeded"
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// FetchJSON performs a GET request and returns the raw response body.
+// Non-200 responses are turned into errors; 429 yields a *RateLimitError.
+func FetchJSON(ctx context.Context, client HTTPClient, url string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("User-Agent", "Netra/1.0")
+	req.Header.Set("Accept", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -32,23 +41,28 @@ func FetchIPInfo(client *CustomHTTPClient, baseURL, ip string) (*formatter.IPInf
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusTooManyRequests {
-		retryAfter := resp.Header.Get("Retry-After")
-		return nil, fmt.Errorf("rate limit exceeded. retry after: %s", retryAfter)
+		return nil, &RateLimitError{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
+	return io.ReadAll(resp.Body)
+}
 
-	var info formatter.IPInfo
-	if err := info.FromJSON(body); err != nil {
-		return nil, err
+// parseRetryAfter understands both forms of the Retry-After header (seconds or HTTP date)
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
 	}
-
-	return &info, nil
+	if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
 }
@@ -0,0 +1,211 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate is a request quota such as 45 per minute. The zero Rate means unlimited.
+type Rate struct {
+	Count int
+	Per   time.Duration
+}
+
+// rateUnits maps the unit part of a rate string to its period
+var rateUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "second": time.Second,
+	"m": time.Minute, "min": time.Minute, "minute": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hour": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+}
+
+// ParseRate parses quotas like "45/min", "1000/day" or "10/s".
+// An empty string, "0" or "unlimited" returns the zero Rate.
+func ParseRate(s string) (Rate, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "0" || s == "unlimited" || s == "none" {
+		return Rate{}, nil
+	}
+
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return Rate{}, fmt.Errorf("invalid rate %q (expected e.g. 45/min)", s)
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || count <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: count must be a positive integer", s)
+	}
+
+	per, ok := rateUnits[strings.TrimSuffix(strings.TrimSpace(parts[1]), "s")]
+	if !ok {
+		per, ok = rateUnits[strings.TrimSpace(parts[1])]
+	}
+	if !ok {
+		return Rate{}, fmt.Errorf("invalid rate %q: unknown unit (use s, min, hour, day or month)", s)
+	}
+	return Rate{Count: count, Per: per}, nil
+}
+
+// IsZero reports whether the rate is unlimited
+func (r Rate) IsZero() bool {
+	return r.Count <= 0 || r.Per <= 0
+}
+
+func (r Rate) String() string {
+	if r.IsZero() {
+		return "unlimited"
+	}
+	for _, unit := range []string{"s", "min", "hour", "day", "month"} {
+		if rateUnits[unit] == r.Per {
+			return fmt.Sprintf("%d/%s", r.Count, unit)
+		}
+	}
+	return fmt.Sprintf("%d/%s", r.Count, r.Per)
+}
+
+// interval is the time it takes to earn one token
+func (r Rate) interval() time.Duration {
+	return r.Per / time.Duration(r.Count)
+}
+
+// RateLimiter is a token bucket holding up to Rate.Count tokens that refills
+// at Rate.Count per Rate.Per. It is safe for concurrent use, so every lookup
+// against the same API can share one limiter.
+type RateLimiter struct {
+	mu          sync.Mutex
+	rate        Rate
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+// NewRateLimiter returns a full bucket for the given rate; a zero rate never
+// blocks. The bucket lives as long as the process, so it paces a run rather
+// than tracking the quota across runs; the API's 429 answers do that.
+func NewRateLimiter(rate Rate) *RateLimiter {
+	return &RateLimiter{rate: rate, tokens: float64(rate.Count), last: time.Now()}
+}
+
+// Rate returns the configured quota
+func (l *RateLimiter) Rate() Rate {
+	return l.rate
+}
+
+// refill adds the tokens earned since the last call; l.mu must be held
+func (l *RateLimiter) refill(now time.Time) {
+	if now.After(l.last) {
+		l.tokens += now.Sub(l.last).Seconds() * float64(l.rate.Count) / l.rate.Per.Seconds()
+		if max := float64(l.rate.Count); l.tokens > max {
+			l.tokens = max
+		}
+		l.last = now
+	}
+}
+
+// delay returns how long until a token is available; l.mu must be held
+func (l *RateLimiter) delay(now time.Time) time.Duration {
+	var d time.Duration
+	if !l.rate.IsZero() && l.tokens < 1 {
+		d = time.Duration((1 - l.tokens) * float64(l.rate.interval()))
+	}
+	if pause := l.pausedUntil.Sub(now); pause > d {
+		d = pause
+	}
+	return d
+}
+
+// Allow takes a token if one is available right now. Otherwise it returns
+// false and how long the caller would have to wait.
+func (l *RateLimiter) Allow() (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if !l.rate.IsZero() {
+		l.refill(now)
+	}
+	if d := l.delay(now); d > 0 {
+		return d, false
+	}
+	if !l.rate.IsZero() {
+		l.tokens--
+	}
+	return 0, true
+}
+
+// Wait blocks until a token is available or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		d, ok := l.Allow()
+		if ok {
+			return nil
+		}
+
+		if err := SleepContext(ctx, d); err != nil {
+			return err
+		}
+	}
+}
+
+// AllowRetry is Allow for a retry of a failed request: it takes a token if
+// one is available but only holds the retry back while the limiter is paused,
+// so retries never wait for the spacing between tokens
+func (l *RateLimiter) AllowRetry() (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if pause := l.pausedUntil.Sub(now); pause > 0 {
+		return pause, false
+	}
+	if !l.rate.IsZero() {
+		l.refill(now)
+		if l.tokens >= 1 {
+			l.tokens--
+		}
+	}
+	return 0, true
+}
+
+// WaitRetry blocks until AllowRetry lets the retry through or ctx is done
+func (l *RateLimiter) WaitRetry(ctx context.Context) error {
+	for {
+		d, ok := l.AllowRetry()
+		if ok {
+			return nil
+		}
+
+		if err := SleepContext(ctx, d); err != nil {
+			return err
+		}
+	}
+}
+
+// Pause stops handing out tokens for d, e.g. after the API answered 429 with
+// Retry-After. Without a Retry-After the caller should pass 0, which pauses
+// for one token interval and drains the bucket so requests slow to the quota.
+func (l *RateLimiter) Pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if d <= 0 {
+		if l.rate.IsZero() {
+			return
+		}
+		d = l.rate.interval()
+	}
+	if until := now.Add(d); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+	if !l.rate.IsZero() {
+		l.refill(now)
+		l.tokens = 0
+	}
+}
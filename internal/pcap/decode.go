@@ -0,0 +1,168 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"net/netip"
+)
+
+// Transport protocol numbers decoded for ports
+const (
+	ProtoTCP = 6
+	ProtoUDP = 17
+)
+
+// Flow is the network and transport addressing of one packet. Ports are
+// zero when the packet is neither TCP nor UDP or is a later fragment.
+type Flow struct {
+	Src, Dst         netip.Addr
+	Protocol         uint8
+	SrcPort, DstPort uint16
+}
+
+// EtherTypes of the frames decoded
+const (
+	etherTypeIPv4  = 0x0800
+	etherTypeIPv6  = 0x86dd
+	etherTypeVLAN  = 0x8100
+	etherTypeQinQ  = 0x88a8
+	etherTypeQinQ2 = 0x9100
+)
+
+// Decode extracts the flow from a captured frame. ok is false for frames
+// that carry no IPv4 or IPv6 packet (ARP, LLDP, ...) or are too short.
+func Decode(linkType uint32, data []byte) (Flow, bool) {
+	switch linkType {
+	case LinkTypeEthernet:
+		return decodeEthernet(data)
+	case LinkTypeRaw, LinkTypeIPv4, LinkTypeIPv6:
+		return decodeIP(data)
+	case LinkTypeLinuxSLL:
+		if len(data) < 16 {
+			return Flow{}, false
+		}
+		return decodeEtherType(binary.BigEndian.Uint16(data[14:16]), data[16:])
+	case LinkTypeNull, LinkTypeLoop:
+		// A 4-byte address family in host (NULL) or network (LOOP) order;
+		// the IP version nibble is enough to tell the payload apart
+		if len(data) < 4 {
+			return Flow{}, false
+		}
+		return decodeIP(data[4:])
+	}
+	return Flow{}, false
+}
+
+func decodeEthernet(data []byte) (Flow, bool) {
+	if len(data) < 14 {
+		return Flow{}, false
+	}
+	etherType := binary.BigEndian.Uint16(data[12:14])
+	data = data[14:]
+
+	// Skip 802.1Q and 802.1ad VLAN tags
+	for etherType == etherTypeVLAN || etherType == etherTypeQinQ || etherType == etherTypeQinQ2 {
+		if len(data) < 4 {
+			return Flow{}, false
+		}
+		etherType = binary.BigEndian.Uint16(data[2:4])
+		data = data[4:]
+	}
+	return decodeEtherType(etherType, data)
+}
+
+func decodeEtherType(etherType uint16, data []byte) (Flow, bool) {
+	switch etherType {
+	case etherTypeIPv4, etherTypeIPv6:
+		return decodeIP(data)
+	}
+	return Flow{}, false
+}
+
+// decodeIP decodes an IPv4 or IPv6 packet, chosen by its version nibble
+func decodeIP(data []byte) (Flow, bool) {
+	if len(data) < 1 {
+		return Flow{}, false
+	}
+	switch data[0] >> 4 {
+	case 4:
+		return decodeIPv4(data)
+	case 6:
+		return decodeIPv6(data)
+	}
+	return Flow{}, false
+}
+
+func decodeIPv4(data []byte) (Flow, bool) {
+	if len(data) < 20 {
+		return Flow{}, false
+	}
+	ihl := int(data[0]&0x0f) * 4
+	if ihl < 20 || len(data) < ihl {
+		return Flow{}, false
+	}
+
+	f := Flow{
+		Src:      netip.AddrFrom4([4]byte{data[12], data[13], data[14], data[15]}),
+		Dst:      netip.AddrFrom4([4]byte{data[16], data[17], data[18], data[19]}),
+		Protocol: data[9],
+	}
+	// Only the first fragment carries the transport header
+	if binary.BigEndian.Uint16(data[6:8])&0x1fff == 0 {
+		f.SrcPort, f.DstPort = decodePorts(f.Protocol, data[ihl:])
+	}
+	return f, true
+}
+
+func decodeIPv6(data []byte) (Flow, bool) {
+	if len(data) < 40 {
+		return Flow{}, false
+	}
+	var src, dst [16]byte
+	copy(src[:], data[8:24])
+	copy(dst[:], data[24:40])
+	f := Flow{Src: netip.AddrFrom16(src), Dst: netip.AddrFrom16(dst)}
+
+	// Walk the extension headers to the transport protocol
+	next, payload := data[6], data[40:]
+	for {
+		switch next {
+		case 0, 43, 60: // hop-by-hop, routing, destination options
+			if len(payload) < 8 {
+				f.Protocol = next
+				return f, true
+			}
+			n := (int(payload[1]) + 1) * 8
+			if len(payload) < n {
+				f.Protocol = next
+				return f, true
+			}
+			next, payload = payload[0], payload[n:]
+			continue
+		case 44: // fragment
+			if len(payload) < 8 {
+				f.Protocol = next
+				return f, true
+			}
+			first := binary.BigEndian.Uint16(payload[2:4])&0xfff8 == 0
+			next, payload = payload[0], payload[8:]
+			if !first {
+				f.Protocol = next
+				return f, true
+			}
+			continue
+		}
+		break
+	}
+
+	f.Protocol = next
+	f.SrcPort, f.DstPort = decodePorts(next, payload)
+	return f, true
+}
+
+// decodePorts reads the ports at the start of a TCP or UDP header
+func decodePorts(proto uint8, data []byte) (uint16, uint16) {
+	if (proto != ProtoTCP && proto != ProtoUDP) || len(data) < 4 {
+		return 0, 0
+	}
+	return binary.BigEndian.Uint16(data[0:2]), binary.BigEndian.Uint16(data[2:4])
+}
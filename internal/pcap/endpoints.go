@@ -0,0 +1,105 @@
+package pcap
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"time"
+)
+
+// Endpoint is the traffic seen to or from one address
+type Endpoint struct {
+	Addr      netip.Addr
+	Packets   int
+	Bytes     int64
+	FirstSeen time.Time
+	LastSeen  time.Time
+
+	// Ports counts the service ports ("443/tcp") of the conversations the
+	// address took part in
+	Ports map[string]int
+}
+
+// Endpoints collects per-address statistics from decoded packets
+type Endpoints struct {
+	byAddr map[netip.Addr]*Endpoint
+	keep   func(netip.Addr) bool
+}
+
+// NewEndpoints returns an empty collection; keep (if not nil) decides which
+// addresses are recorded
+func NewEndpoints(keep func(netip.Addr) bool) *Endpoints {
+	return &Endpoints{byAddr: make(map[netip.Addr]*Endpoint), keep: keep}
+}
+
+// Add records one packet for both its source and destination
+func (e *Endpoints) Add(p Packet, f Flow) {
+	service := servicePort(f)
+	for _, addr := range []netip.Addr{f.Src, f.Dst} {
+		addr = addr.Unmap()
+		if !addr.IsValid() || (e.keep != nil && !e.keep(addr)) {
+			continue
+		}
+
+		ep, ok := e.byAddr[addr]
+		if !ok {
+			ep = &Endpoint{Addr: addr, Ports: make(map[string]int)}
+			e.byAddr[addr] = ep
+		}
+		ep.Packets++
+		ep.Bytes += int64(p.Length)
+		if !p.Time.IsZero() {
+			if ep.FirstSeen.IsZero() || p.Time.Before(ep.FirstSeen) {
+				ep.FirstSeen = p.Time
+			}
+			if p.Time.After(ep.LastSeen) {
+				ep.LastSeen = p.Time
+			}
+		}
+		if service != "" {
+			ep.Ports[service]++
+		}
+	}
+}
+
+// Len returns the number of distinct addresses recorded
+func (e *Endpoints) Len() int {
+	return len(e.byAddr)
+}
+
+// Results returns the endpoints, most bytes first
+func (e *Endpoints) Results() []*Endpoint {
+	out := make([]*Endpoint, 0, len(e.byAddr))
+	for _, ep := range e.byAddr {
+		out = append(out, ep)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Bytes != out[j].Bytes {
+			return out[i].Bytes > out[j].Bytes
+		}
+		return out[i].Addr.Less(out[j].Addr)
+	})
+	return out
+}
+
+// servicePort guesses the service side of a TCP or UDP conversation as the
+// lower of its two ports, which is right for nearly all client/server traffic
+func servicePort(f Flow) string {
+	var proto string
+	switch f.Protocol {
+	case ProtoTCP:
+		proto = "tcp"
+	case ProtoUDP:
+		proto = "udp"
+	default:
+		return ""
+	}
+	port := f.SrcPort
+	if f.DstPort != 0 && (port == 0 || f.DstPort < port) {
+		port = f.DstPort
+	}
+	if port == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d/%s", port, proto)
+}
@@ -0,0 +1,290 @@
+// Package pcap reads packet captures in the classic libpcap and pcapng
+// formats without cgo or libpcap.
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Link types (https://www.tcpdump.org/linktypes.html) the decoder understands
+const (
+	LinkTypeNull     = 0
+	LinkTypeEthernet = 1
+	LinkTypeRaw      = 101
+	LinkTypeLoop     = 108
+	LinkTypeLinuxSLL = 113
+	LinkTypeIPv4     = 228
+	LinkTypeIPv6     = 229
+)
+
+// maxPacketSize guards against corrupt length fields allocating gigabytes
+const maxPacketSize = 256 * 1024
+
+// Packet is one captured frame
+type Packet struct {
+	Time     time.Time
+	LinkType uint32
+	Data     []byte // captured bytes, possibly truncated to the snap length
+	Length   int    // length of the frame on the wire
+}
+
+// Reader returns the packets of a pcap or pcapng stream in order
+type Reader struct {
+	r    *bufio.Reader
+	next func() (Packet, error)
+
+	// classic pcap
+	order    binary.ByteOrder
+	linkType uint32
+	tsUnit   time.Duration
+
+	// pcapng, per section
+	interfaces []ngInterface
+}
+
+// ngInterface is what a pcapng Interface Description Block declares
+type ngInterface struct {
+	linkType uint32
+	tsUnit   time.Duration
+}
+
+// NewReader detects the capture format from its magic number
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("not a packet capture: %v", err)
+	}
+
+	rd := &Reader{r: br}
+	switch {
+	case binary.BigEndian.Uint32(magic) == ngBlockSHB:
+		rd.next = rd.nextNG
+		return rd, nil
+	default:
+		if err := rd.readPcapHeader(); err != nil {
+			return nil, err
+		}
+		rd.next = rd.nextPcap
+		return rd, nil
+	}
+}
+
+// Next returns the next packet, or io.EOF after the last one
+func (rd *Reader) Next() (Packet, error) {
+	return rd.next()
+}
+
+// readPcapHeader parses the 24-byte libpcap global header
+func (rd *Reader) readPcapHeader() error {
+	var hdr [24]byte
+	if _, err := io.ReadFull(rd.r, hdr[:]); err != nil {
+		return fmt.Errorf("not a packet capture: %v", err)
+	}
+
+	switch binary.LittleEndian.Uint32(hdr[0:4]) {
+	case 0xa1b2c3d4:
+		rd.order, rd.tsUnit = binary.LittleEndian, time.Microsecond
+	case 0xa1b23c4d:
+		rd.order, rd.tsUnit = binary.LittleEndian, time.Nanosecond
+	case 0xd4c3b2a1:
+		rd.order, rd.tsUnit = binary.BigEndian, time.Microsecond
+	case 0x4d3cb2a1:
+		rd.order, rd.tsUnit = binary.BigEndian, time.Nanosecond
+	default:
+		return errors.New("not a pcap or pcapng file")
+	}
+	// The upper bits of the link type field carry FCS flags
+	rd.linkType = rd.order.Uint32(hdr[20:24]) & 0x0fffffff
+	return nil
+}
+
+func (rd *Reader) nextPcap() (Packet, error) {
+	var hdr [16]byte
+	if _, err := io.ReadFull(rd.r, hdr[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return Packet{}, fmt.Errorf("truncated packet header")
+		}
+		return Packet{}, err
+	}
+
+	sec := rd.order.Uint32(hdr[0:4])
+	frac := rd.order.Uint32(hdr[4:8])
+	capLen := rd.order.Uint32(hdr[8:12])
+	origLen := rd.order.Uint32(hdr[12:16])
+	if capLen > maxPacketSize {
+		return Packet{}, fmt.Errorf("corrupt packet length %d", capLen)
+	}
+
+	data := make([]byte, capLen)
+	if _, err := io.ReadFull(rd.r, data); err != nil {
+		return Packet{}, fmt.Errorf("truncated packet: %v", err)
+	}
+	return Packet{
+		Time:     time.Unix(int64(sec), int64(frac)*int64(rd.tsUnit)).UTC(),
+		LinkType: rd.linkType,
+		Data:     data,
+		Length:   int(origLen),
+	}, nil
+}
+
+// pcapng block types
+const (
+	ngBlockSHB = 0x0a0d0d0a // Section Header
+	ngBlockIDB = 0x00000001 // Interface Description
+	ngBlockSPB = 0x00000003 // Simple Packet
+	ngBlockEPB = 0x00000006 // Enhanced Packet
+	ngBlockOPB = 0x00000002 // (obsolete) Packet
+
+	ngByteOrderMagic = 0x1a2b3c4d
+	ngOptTSResol     = 9
+	ngMaxBlockSize   = 16 * 1024 * 1024
+)
+
+func (rd *Reader) nextNG() (Packet, error) {
+	for {
+		blockType, body, err := rd.readNGBlock()
+		if err != nil {
+			return Packet{}, err
+		}
+
+		switch blockType {
+		case ngBlockIDB:
+			if len(body) < 8 {
+				return Packet{}, errors.New("corrupt pcapng interface block")
+			}
+			iface := ngInterface{linkType: uint32(rd.order.Uint16(body[0:2])), tsUnit: time.Microsecond}
+			if unit, ok := rd.tsResolution(body[8:]); ok {
+				iface.tsUnit = unit
+			}
+			rd.interfaces = append(rd.interfaces, iface)
+
+		case ngBlockEPB, ngBlockOPB:
+			if len(body) < 20 {
+				return Packet{}, errors.New("corrupt pcapng packet block")
+			}
+			var id uint32
+			if blockType == ngBlockEPB {
+				id = rd.order.Uint32(body[0:4])
+			} else {
+				id = uint32(rd.order.Uint16(body[0:2]))
+			}
+			if int(id) >= len(rd.interfaces) {
+				return Packet{}, fmt.Errorf("pcapng packet refers to unknown interface %d", id)
+			}
+			iface := rd.interfaces[id]
+			ts := uint64(rd.order.Uint32(body[4:8]))<<32 | uint64(rd.order.Uint32(body[8:12]))
+			capLen := rd.order.Uint32(body[12:16])
+			origLen := rd.order.Uint32(body[16:20])
+			if int(capLen) > len(body)-20 {
+				return Packet{}, errors.New("corrupt pcapng packet length")
+			}
+			return Packet{
+				Time:     ngTime(ts, iface.tsUnit),
+				LinkType: iface.linkType,
+				Data:     body[20 : 20+capLen],
+				Length:   int(origLen),
+			}, nil
+
+		case ngBlockSPB:
+			if len(body) < 4 || len(rd.interfaces) == 0 {
+				return Packet{}, errors.New("corrupt pcapng simple packet block")
+			}
+			origLen := int(rd.order.Uint32(body[0:4]))
+			data := body[4:]
+			if origLen < len(data) {
+				data = data[:origLen]
+			}
+			return Packet{LinkType: rd.interfaces[0].linkType, Data: data, Length: origLen}, nil
+		}
+		// Statistics, name resolution and custom blocks carry no packets
+	}
+}
+
+// readNGBlock reads one pcapng block and returns its body (without the
+// type and the two length fields). A Section Header Block resets the byte
+// order and the interface list.
+func (rd *Reader) readNGBlock() (uint32, []byte, error) {
+	var hdr [8]byte
+	if _, err := io.ReadFull(rd.r, hdr[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, nil, errors.New("truncated pcapng block")
+		}
+		return 0, nil, err
+	}
+
+	// The section header's type reads the same in both byte orders; its
+	// byte-order magic decides how the rest of the section is read
+	if binary.BigEndian.Uint32(hdr[0:4]) == ngBlockSHB {
+		magic, err := rd.r.Peek(4)
+		if err != nil {
+			return 0, nil, errors.New("truncated pcapng section header")
+		}
+		if binary.LittleEndian.Uint32(magic) == ngByteOrderMagic {
+			rd.order = binary.LittleEndian
+		} else if binary.BigEndian.Uint32(magic) == ngByteOrderMagic {
+			rd.order = binary.BigEndian
+		} else {
+			return 0, nil, errors.New("corrupt pcapng section header")
+		}
+		rd.interfaces = nil
+	}
+	if rd.order == nil {
+		return 0, nil, errors.New("pcapng block before section header")
+	}
+
+	blockType := rd.order.Uint32(hdr[0:4])
+	total := rd.order.Uint32(hdr[4:8])
+	if total < 12 || total%4 != 0 || total > ngMaxBlockSize {
+		return 0, nil, fmt.Errorf("corrupt pcapng block length %d", total)
+	}
+
+	rest := make([]byte, total-8)
+	if _, err := io.ReadFull(rd.r, rest); err != nil {
+		return 0, nil, errors.New("truncated pcapng block")
+	}
+	// Drop the trailing copy of the block length
+	return blockType, rest[:len(rest)-4], nil
+}
+
+// tsResolution reads the if_tsresol option of an interface block
+func (rd *Reader) tsResolution(opts []byte) (time.Duration, bool) {
+	for len(opts) >= 4 {
+		code := rd.order.Uint16(opts[0:2])
+		length := int(rd.order.Uint16(opts[2:4]))
+		if code == 0 || 4+length > len(opts) {
+			return 0, false
+		}
+		if code == ngOptTSResol && length >= 1 {
+			v := opts[4]
+			if v&0x80 != 0 {
+				// Negative powers of two are rare; approximate to the nearest nanosecond
+				return time.Duration(float64(time.Second) / float64(uint64(1)<<(v&0x7f))), true
+			}
+			unit := time.Second
+			for i := byte(0); i < v && unit > time.Nanosecond; i++ {
+				unit /= 10
+			}
+			return unit, true
+		}
+		opts = opts[4+(length+3)/4*4:]
+	}
+	return 0, false
+}
+
+// ngTime converts a pcapng timestamp in units of unit since the epoch
+func ngTime(ts uint64, unit time.Duration) time.Time {
+	if unit <= 0 {
+		unit = time.Microsecond
+	}
+	perSec := uint64(time.Second / unit)
+	if perSec == 0 {
+		perSec = 1
+	}
+	return time.Unix(int64(ts/perSec), int64(ts%perSec)*int64(unit)).UTC()
+}
@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+)
+
+// chainProvider tries each provider in order and returns the first answer,
+// so a rate-limited or unreachable API falls through to the next one
+type chainProvider struct {
+	providers []Provider
+}
+
+// NewChain returns a provider that falls back through providers in order
+func NewChain(providers ...Provider) Provider {
+	if len(providers) == 1 {
+		return providers[0]
+	}
+	return &chainProvider{providers: providers}
+}
+
+func (c *chainProvider) Name() string {
+	return "chain(" + strings.Join(memberNames(c.providers), ",") + ")"
+}
+
+func (c *chainProvider) Capabilities() Capabilities {
+	return combinedCapabilities(c.providers)
+}
+
+func (c *chainProvider) Lookup(ctx context.Context, ip string) (*formatter.IPInfo, error) {
+	var errs []string
+	for _, p := range c.providers {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		info, err := p.Lookup(ctx, ip)
+		if err == nil {
+			return info, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", p.Name(), err))
+	}
+	return nil, fmt.Errorf("all providers failed: %s", strings.Join(errs, "; "))
+}
+
+func memberNames(providers []Provider) []string {
+	names := make([]string, len(providers))
+	for i, p := range providers {
+		names[i] = p.Name()
+	}
+	return names
+}
+
+// combinedCapabilities reports a field as supported if any member supports it;
+// the combination is only local if every member is
+func combinedCapabilities(providers []Provider) Capabilities {
+	caps := Capabilities{Local: true}
+	for _, p := range providers {
+		c := p.Capabilities()
+		caps.ASN = caps.ASN || c.ASN
+		caps.Proxy = caps.Proxy || c.Proxy
+		caps.Mobile = caps.Mobile || c.Mobile
+		caps.Hosting = caps.Hosting || c.Hosting
+		caps.RequiresToken = caps.RequiresToken || c.RequiresToken
+		caps.Local = caps.Local && c.Local
+	}
+	return caps
+}
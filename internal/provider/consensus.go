@@ -0,0 +1,195 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+)
+
+// consensusFields are the IPInfo fields decided by majority vote
+var consensusFields = []string{
+	"country", "country_code", "continent", "region", "city", "postal",
+	"latitude", "timezone", "isp", "org", "asn",
+	"is_mobile", "is_proxy", "is_hosting",
+}
+
+// consensusProvider queries every provider in parallel and merges the
+// answers field by field, recording where each value came from
+type consensusProvider struct {
+	providers []Provider
+}
+
+// NewConsensus returns a provider that merges the answers of all providers by majority vote
+func NewConsensus(providers ...Provider) Provider {
+	return &consensusProvider{providers: providers}
+}
+
+func (c *consensusProvider) Name() string {
+	return "consensus(" + strings.Join(memberNames(c.providers), ",") + ")"
+}
+
+func (c *consensusProvider) Capabilities() Capabilities {
+	return combinedCapabilities(c.providers)
+}
+
+type answer struct {
+	provider Provider
+	info     *formatter.IPInfo
+	err      error
+}
+
+func (c *consensusProvider) Lookup(ctx context.Context, ip string) (*formatter.IPInfo, error) {
+	answers := make([]answer, len(c.providers))
+
+	var wg sync.WaitGroup
+	for i, p := range c.providers {
+		wg.Add(1)
+		go func(i int, p Provider) {
+			defer wg.Done()
+			info, err := p.Lookup(ctx, ip)
+			answers[i] = answer{provider: p, info: info, err: err}
+		}(i, p)
+	}
+	wg.Wait()
+
+	var ok []answer
+	var errs []string
+	for _, a := range answers {
+		if a.err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", a.provider.Name(), a.err))
+			continue
+		}
+		ok = append(ok, a)
+	}
+	if len(ok) == 0 {
+		return nil, fmt.Errorf("all providers failed: %s", strings.Join(errs, "; "))
+	}
+
+	return merge(ip, ok), nil
+}
+
+// vote is one distinct value for a field and the providers that reported it
+type vote struct {
+	value  interface{}
+	voters []string
+}
+
+// merge picks, for every field, the value reported by the most providers.
+// Ties go to the provider listed first. Empty values do not vote, and boolean
+// flags only count from providers that actually detect them. Latitude and
+// longitude are voted on as one location so they always come from the same answer.
+func merge(ip string, answers []answer) *formatter.IPInfo {
+	merged := &formatter.IPInfo{
+		IP:            ip,
+		Sources:       make(map[string]string),
+		Disagreements: make(map[string]map[string]string),
+	}
+
+	maps := make([]map[string]interface{}, len(answers))
+	for i, a := range answers {
+		maps[i] = a.info.ToMap()
+	}
+
+	for _, field := range consensusFields {
+		var votes []*vote
+		byKey := make(map[string]*vote)
+		reported := make(map[string]string)
+
+		for i, a := range answers {
+			if !canReport(a.provider.Capabilities(), field) {
+				continue
+			}
+			value := maps[i][field]
+			key := voteKey(value)
+			if field == "latitude" {
+				value = [2]interface{}{value, maps[i]["longitude"]}
+				key += "," + voteKey(maps[i]["longitude"])
+			}
+			if key == "" || key == "," {
+				continue
+			}
+
+			name := a.provider.Name()
+			reported[name] = formatVote(value)
+			v, exists := byKey[key]
+			if !exists {
+				v = &vote{value: value}
+				byKey[key] = v
+				votes = append(votes, v)
+			}
+			v.voters = append(v.voters, name)
+		}
+
+		if len(votes) == 0 {
+			continue
+		}
+
+		winner := votes[0]
+		for _, v := range votes[1:] {
+			if len(v.voters) > len(winner.voters) {
+				winner = v
+			}
+		}
+
+		sources := strings.Join(winner.voters, "+")
+		if loc, isLoc := winner.value.([2]interface{}); isLoc {
+			merged.SetField("latitude", loc[0])
+			merged.SetField("longitude", loc[1])
+			merged.Sources["longitude"] = sources
+		} else {
+			merged.SetField(field, winner.value)
+		}
+		merged.Sources[field] = sources
+		if len(votes) > 1 {
+			merged.Disagreements[field] = reported
+		}
+	}
+
+	names := make([]string, len(answers))
+	for i, a := range answers {
+		names[i] = a.provider.Name()
+	}
+	merged.Provider = strings.Join(names, "+")
+	return merged
+}
+
+// canReport tells whether a provider's value for field is meaningful
+func canReport(caps Capabilities, field string) bool {
+	switch field {
+	case "asn":
+		return caps.ASN
+	case "is_proxy":
+		return caps.Proxy
+	case "is_mobile":
+		return caps.Mobile
+	case "is_hosting":
+		return caps.Hosting
+	}
+	return true
+}
+
+// voteKey normalizes a value so equivalent answers are counted together.
+// Coordinates agree when they are within roughly 10 km.
+func voteKey(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		if v == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.1f", math.Round(v*10)/10)
+	case string:
+		return strings.ToLower(strings.TrimSpace(v))
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func formatVote(value interface{}) string {
+	if loc, ok := value.([2]interface{}); ok {
+		return fmt.Sprintf("%v,%v", loc[0], loc[1])
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"github.com/ODIN7h3C0d3r/Netra/internal/network"
+)
+
+const ipapiBaseURL = "https://ipapi.co"
+
+// ipapiProvider queries ipapi.co (https://ipapi.co/<ip>/json/)
+type ipapiProvider struct {
+	name    string
+	baseURL string
+	token   string
+	client  network.HTTPClient
+}
+
+type ipapiResponse struct {
+	IP            string  `json:"ip"`
+	City          string  `json:"city"`
+	Region        string  `json:"region"`
+	CountryName   string  `json:"country_name"`
+	CountryCode   string  `json:"country_code"`
+	ContinentCode string  `json:"continent_code"`
+	Postal        string  `json:"postal"`
+	Latitude      float64 `json:"latitude"`
+	Longitude     float64 `json:"longitude"`
+	Timezone      string  `json:"timezone"`
+	ASN           string  `json:"asn"`
+	Org           string  `json:"org"`
+	Error         bool    `json:"error"`
+	Reason        string  `json:"reason"`
+}
+
+func newIPAPI(name string, s Settings, client network.HTTPClient) (Provider, error) {
+	baseURL := s.BaseURL
+	if baseURL == "" {
+		baseURL = ipapiBaseURL
+	}
+	return &ipapiProvider{name: name, baseURL: strings.TrimSuffix(baseURL, "/"), token: s.Token, client: client}, nil
+}
+
+func (p *ipapiProvider) Name() string { return p.name }
+
+// ipapi.co allows 1,000 requests per day without a paid plan
+func (p *ipapiProvider) Capabilities() Capabilities {
+	caps := Capabilities{ASN: true}
+	if p.token == "" {
+		caps.Quota = network.Rate{Count: 1000, Per: 24 * time.Hour}
+	}
+	return caps
+}
+
+func (p *ipapiProvider) Lookup(ctx context.Context, ip string) (*formatter.IPInfo, error) {
+	endpoint := p.baseURL + "/"
+	if ip != "" {
+		endpoint += url.PathEscape(ip) + "/"
+	}
+	endpoint += "json/"
+	if p.token != "" {
+		endpoint += "?key=" + url.QueryEscape(p.token)
+	}
+
+	body, err := network.FetchJSON(ctx, p.client, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw ipapiResponse
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse %s response: %v", p.name, err)
+	}
+	if raw.Error {
+		return nil, fmt.Errorf("%s: %s", p.name, raw.Reason)
+	}
+
+	info := &formatter.IPInfo{
+		IP:          raw.IP,
+		Country:     raw.CountryName,
+		CountryCode: raw.CountryCode,
+		Region:      raw.Region,
+		City:        raw.City,
+		Latitude:    raw.Latitude,
+		Longitude:   raw.Longitude,
+		Timezone:    raw.Timezone,
+		ISP:         raw.Org,
+		Postal:      raw.Postal,
+		ASN:         raw.ASN,
+		Org:         raw.Org,
+		Continent:   continentName(raw.ContinentCode),
+		Provider:    p.name,
+	}
+	info.Normalize()
+	return info, nil
+}
@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"github.com/ODIN7h3C0d3r/Netra/internal/network"
+)
+
+// The free ip-api.com endpoint is HTTP only; keyed requests go to pro.ip-api.com
+const (
+	ipapiComBaseURL    = "http://ip-api.com"
+	ipapiComProBaseURL = "https://pro.ip-api.com"
+	ipapiComFields     = "status,message,continent,country,countryCode,regionName,city,zip,lat,lon,timezone,isp,org,as,mobile,proxy,hosting,query"
+)
+
+// ipapiComProvider queries ip-api.com (http://ip-api.com/json/<ip>)
+type ipapiComProvider struct {
+	name    string
+	baseURL string
+	token   string
+	client  network.HTTPClient
+}
+
+type ipapiComResponse struct {
+	Status      string  `json:"status"`
+	Message     string  `json:"message"`
+	Query       string  `json:"query"`
+	Continent   string  `json:"continent"`
+	Country     string  `json:"country"`
+	CountryCode string  `json:"countryCode"`
+	RegionName  string  `json:"regionName"`
+	City        string  `json:"city"`
+	Zip         string  `json:"zip"`
+	Lat         float64 `json:"lat"`
+	Lon         float64 `json:"lon"`
+	Timezone    string  `json:"timezone"`
+	ISP         string  `json:"isp"`
+	Org         string  `json:"org"`
+	AS          string  `json:"as"`
+	Mobile      bool    `json:"mobile"`
+	Proxy       bool    `json:"proxy"`
+	Hosting     bool    `json:"hosting"`
+}
+
+func newIPAPICom(name string, s Settings, client network.HTTPClient) (Provider, error) {
+	baseURL := s.BaseURL
+	if baseURL == "" {
+		baseURL = ipapiComBaseURL
+		if s.Token != "" {
+			baseURL = ipapiComProBaseURL
+		}
+	}
+	return &ipapiComProvider{name: name, baseURL: strings.TrimSuffix(baseURL, "/"), token: s.Token, client: client}, nil
+}
+
+func (p *ipapiComProvider) Name() string { return p.name }
+
+// The free ip-api.com endpoint allows 45 requests per minute; pro is unlimited
+func (p *ipapiComProvider) Capabilities() Capabilities {
+	caps := Capabilities{ASN: true, Proxy: true, Mobile: true, Hosting: true}
+	if p.token == "" {
+		caps.Quota = network.Rate{Count: 45, Per: time.Minute}
+	}
+	return caps
+}
+
+func (p *ipapiComProvider) Lookup(ctx context.Context, ip string) (*formatter.IPInfo, error) {
+	query := url.Values{}
+	query.Set("fields", ipapiComFields)
+	if p.token != "" {
+		query.Set("key", p.token)
+	}
+	endpoint := p.baseURL + "/json/" + url.PathEscape(ip) + "?" + query.Encode()
+
+	body, err := network.FetchJSON(ctx, p.client, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw ipapiComResponse
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse %s response: %v", p.name, err)
+	}
+	if raw.Status != "success" {
+		return nil, fmt.Errorf("%s: %s", p.name, raw.Message)
+	}
+
+	asn, _ := splitASOrg(raw.AS)
+	info := &formatter.IPInfo{
+		IP:          raw.Query,
+		Country:     raw.Country,
+		CountryCode: raw.CountryCode,
+		Region:      raw.RegionName,
+		City:        raw.City,
+		Latitude:    raw.Lat,
+		Longitude:   raw.Lon,
+		Timezone:    raw.Timezone,
+		ISP:         raw.ISP,
+		Postal:      raw.Zip,
+		ASN:         asn,
+		IsMobile:    raw.Mobile,
+		IsProxy:     raw.Proxy,
+		IsHosting:   raw.Hosting,
+		Org:         raw.Org,
+		Continent:   raw.Continent,
+		Provider:    p.name,
+	}
+	info.Normalize()
+	return info, nil
+}
@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"github.com/ODIN7h3C0d3r/Netra/internal/network"
+)
+
+const ipinfoBaseURL = "https://ipinfo.io"
+
+// ipinfoProvider queries ipinfo.io (https://ipinfo.io/<ip>/json)
+type ipinfoProvider struct {
+	name    string
+	baseURL string
+	token   string
+	client  network.HTTPClient
+}
+
+type ipinfoResponse struct {
+	IP       string `json:"ip"`
+	City     string `json:"city"`
+	Region   string `json:"region"`
+	Country  string `json:"country"`
+	Loc      string `json:"loc"`
+	Org      string `json:"org"`
+	Postal   string `json:"postal"`
+	Timezone string `json:"timezone"`
+	Bogon    bool   `json:"bogon"`
+	ASN      *struct {
+		ASN  string `json:"asn"`
+		Name string `json:"name"`
+	} `json:"asn"`
+	Privacy *struct {
+		VPN     bool `json:"vpn"`
+		Proxy   bool `json:"proxy"`
+		Tor     bool `json:"tor"`
+		Hosting bool `json:"hosting"`
+	} `json:"privacy"`
+	Error *struct {
+		Title   string `json:"title"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func newIPInfo(name string, s Settings, client network.HTTPClient) (Provider, error) {
+	baseURL := s.BaseURL
+	if baseURL == "" {
+		baseURL = ipinfoBaseURL
+	}
+	return &ipinfoProvider{name: name, baseURL: strings.TrimSuffix(baseURL, "/"), token: s.Token, client: client}, nil
+}
+
+func (p *ipinfoProvider) Name() string { return p.name }
+
+// Capabilities reports proxy and hosting detection only when a token is set,
+// since the privacy data is not part of the free tier. Anonymous requests are
+// limited to 1,000 per day and free tokens to 50,000 per month.
+func (p *ipinfoProvider) Capabilities() Capabilities {
+	caps := Capabilities{ASN: true, Proxy: p.token != "", Hosting: p.token != ""}
+	if p.token == "" {
+		caps.Quota = network.Rate{Count: 1000, Per: 24 * time.Hour}
+	} else {
+		caps.Quota = network.Rate{Count: 50000, Per: 30 * 24 * time.Hour}
+	}
+	return caps
+}
+
+func (p *ipinfoProvider) Lookup(ctx context.Context, ip string) (*formatter.IPInfo, error) {
+	endpoint := p.baseURL + "/"
+	if ip != "" {
+		endpoint += url.PathEscape(ip) + "/"
+	}
+	endpoint += "json"
+
+	var headers map[string]string
+	if p.token != "" {
+		headers = map[string]string{"Authorization": "Bearer " + p.token}
+	}
+
+	body, err := network.FetchJSON(ctx, p.client, endpoint, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw ipinfoResponse
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse %s response: %v", p.name, err)
+	}
+	if raw.Error != nil {
+		return nil, fmt.Errorf("%s: %s", p.name, raw.Error.Message)
+	}
+	if raw.Bogon {
+		return nil, fmt.Errorf("%s: %s is a bogon address", p.name, raw.IP)
+	}
+
+	asn, org := splitASOrg(raw.Org)
+	if raw.ASN != nil {
+		asn, org = strings.ToUpper(raw.ASN.ASN), raw.ASN.Name
+	}
+
+	info := &formatter.IPInfo{
+		IP:          raw.IP,
+		Country:     countryName(raw.Country),
+		CountryCode: raw.Country,
+		Region:      raw.Region,
+		City:        raw.City,
+		Timezone:    raw.Timezone,
+		ISP:         org,
+		Postal:      raw.Postal,
+		ASN:         asn,
+		Org:         org,
+		Provider:    p.name,
+	}
+
+	if lat, lon, ok := strings.Cut(raw.Loc, ","); ok {
+		info.Latitude, _ = strconv.ParseFloat(lat, 64)
+		info.Longitude, _ = strconv.ParseFloat(lon, 64)
+	}
+
+	if raw.Privacy != nil {
+		info.IsProxy = raw.Privacy.Proxy || raw.Privacy.VPN || raw.Privacy.Tor
+		info.IsHosting = raw.Privacy.Hosting
+	}
+
+	info.Normalize()
+	return info, nil
+}
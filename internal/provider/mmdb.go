@@ -0,0 +1,164 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"github.com/ODIN7h3C0d3r/Netra/internal/mmdb"
+	"github.com/ODIN7h3C0d3r/Netra/internal/network"
+)
+
+// mmdbProvider answers lookups offline from MaxMind DB files such as
+// GeoLite2-City, GeoLite2-Country, GeoLite2-ASN and GeoIP2-Anonymous-IP
+type mmdbProvider struct {
+	name string
+	geo  []*mmdb.Reader
+	asn  []*mmdb.Reader
+	anon []*mmdb.Reader
+}
+
+func newMMDB(name string, s Settings, _ network.HTTPClient) (Provider, error) {
+	if len(s.Databases) == 0 {
+		return nil, fmt.Errorf("provider %s: no database files configured (use -db or providers.backends.%s.databases)", name, name)
+	}
+
+	p := &mmdbProvider{name: name}
+	for _, path := range s.Databases {
+		r, err := mmdb.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: %v", name, err)
+		}
+		dbType := strings.ToUpper(r.Metadata.DatabaseType)
+		switch {
+		case strings.Contains(dbType, "ASN"):
+			p.asn = append(p.asn, r)
+		case strings.Contains(dbType, "ANONYMOUS-IP"):
+			p.anon = append(p.anon, r)
+		default:
+			p.geo = append(p.geo, r)
+		}
+	}
+	return p, nil
+}
+
+func (p *mmdbProvider) Name() string { return p.name }
+
+// Capabilities follow the loaded databases: only the Anonymous IP database
+// and the commercial GeoIP2 City and Country traits carry proxy data, and
+// only the Anonymous IP database flags hosting providers
+func (p *mmdbProvider) Capabilities() Capabilities {
+	proxy := len(p.anon) > 0
+	for _, r := range p.geo {
+		if strings.HasPrefix(strings.ToUpper(r.Metadata.DatabaseType), "GEOIP2-") {
+			proxy = true
+		}
+	}
+	return Capabilities{ASN: len(p.asn) > 0, Proxy: proxy, Hosting: len(p.anon) > 0, Local: true}
+}
+
+func (p *mmdbProvider) Lookup(_ context.Context, ip string) (*formatter.IPInfo, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return nil, fmt.Errorf("%s: invalid IP address %q", p.name, ip)
+	}
+
+	info := &formatter.IPInfo{IP: ip, Provider: p.name}
+	found := false
+
+	for _, r := range p.geo {
+		rec, err := r.Lookup(addr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", p.name, err)
+		}
+		if rec == nil {
+			continue
+		}
+		found = true
+		fillGeo(info, rec)
+		break
+	}
+
+	for _, r := range p.asn {
+		rec, err := r.Lookup(addr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", p.name, err)
+		}
+		if rec == nil {
+			continue
+		}
+		found = true
+		if n, ok := mmdb.Path(rec, "autonomous_system_number").(uint64); ok && n > 0 {
+			info.ASN = fmt.Sprintf("AS%d", n)
+		}
+		if org, ok := mmdb.Path(rec, "autonomous_system_organization").(string); ok {
+			info.Org = org
+			info.ISP = org
+		}
+		break
+	}
+
+	if !found {
+		return nil, fmt.Errorf("%s: %s not found in database", p.name, ip)
+	}
+
+	// The Anonymous IP database only lists flagged networks, so a miss
+	// means the address is neither a proxy nor a hosting provider
+	for _, r := range p.anon {
+		rec, err := r.Lookup(addr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", p.name, err)
+		}
+		if rec == nil {
+			continue
+		}
+		flag := func(key string) bool {
+			b, _ := mmdb.Path(rec, key).(bool)
+			return b
+		}
+		info.IsProxy = info.IsProxy || flag("is_anonymous") || flag("is_anonymous_vpn") ||
+			flag("is_public_proxy") || flag("is_residential_proxy") || flag("is_tor_exit_node")
+		info.IsHosting = flag("is_hosting_provider")
+		break
+	}
+
+	info.Normalize()
+	return info, nil
+}
+
+// fillGeo copies the GeoIP2/GeoLite2 City or Country record layout into info
+func fillGeo(info *formatter.IPInfo, rec map[string]interface{}) {
+	str := func(keys ...interface{}) string {
+		s, _ := mmdb.Path(rec, keys...).(string)
+		return s
+	}
+	num := func(keys ...interface{}) float64 {
+		f, _ := mmdb.Path(rec, keys...).(float64)
+		return f
+	}
+
+	info.CountryCode = str("country", "iso_code")
+	info.Country = str("country", "names", "en")
+	if info.Country == "" {
+		info.Country = countryName(info.CountryCode)
+	}
+	info.Continent = str("continent", "names", "en")
+	if info.Continent == "" {
+		info.Continent = continentName(str("continent", "code"))
+	}
+	info.Region = str("subdivisions", 0, "names", "en")
+	info.City = str("city", "names", "en")
+	info.Postal = str("postal", "code")
+	info.Latitude = num("location", "latitude")
+	info.Longitude = num("location", "longitude")
+	info.Timezone = str("location", "time_zone")
+
+	if proxy, ok := mmdb.Path(rec, "traits", "is_anonymous_proxy").(bool); ok {
+		info.IsProxy = proxy
+	}
+	if isp := str("traits", "isp"); isp != "" {
+		info.ISP = isp
+	}
+}
@@ -0,0 +1,101 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"github.com/ODIN7h3C0d3r/Netra/internal/network"
+)
+
+// Capabilities describes which optional IPInfo fields a provider can fill
+type Capabilities struct {
+	ASN           bool
+	Proxy         bool
+	Mobile        bool
+	Hosting       bool
+	RequiresToken bool
+
+	// Local providers answer without network access, so lookups skip
+	// the cache and retry logic
+	Local bool
+
+	// Quota is the API's published free-tier limit, used as the default
+	// rate limit; zero means unlimited
+	Quota network.Rate
+}
+
+// Provider looks up geolocation data for a single IP address and
+// normalizes the answer into a formatter.IPInfo
+type Provider interface {
+	Name() string
+	Lookup(ctx context.Context, ip string) (*formatter.IPInfo, error)
+	Capabilities() Capabilities
+}
+
+// Settings configures a provider instance
+type Settings struct {
+	// Type selects the implementation; defaults to the provider name
+	Type    string
+	BaseURL string
+	Token   string
+
+	// URL and Fields are used by the "template" type: URL may contain {ip}
+	// and {token}, Fields maps IPInfo field names to dotted JSON paths
+	URL    string
+	Fields map[string]string
+
+	// Databases lists .mmdb files for the "mmdb" type
+	Databases []string
+}
+
+type factory func(name string, s Settings, client network.HTTPClient) (Provider, error)
+
+var builtins = map[string]factory{
+	"ipapi":    newIPAPI,
+	"ipinfo":   newIPInfo,
+	"ip-api":   newIPAPICom,
+	"template": newTemplate,
+	"mmdb":     newMMDB,
+}
+
+// Names returns the built-in provider types
+func Names() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates a provider by name; s.Type overrides the implementation so
+// several named instances of the same type can coexist
+func New(name string, s Settings, client network.HTTPClient) (Provider, error) {
+	typ := s.Type
+	if typ == "" {
+		typ = name
+	}
+
+	create, ok := builtins[strings.ToLower(typ)]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q (available: %s)", typ, strings.Join(Names(), ", "))
+	}
+	return create(name, s, client)
+}
+
+// splitASOrg splits strings like "AS15169 Google LLC" into ASN and organization
+func splitASOrg(s string) (asn, org string) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(strings.ToUpper(s), "AS") {
+		return "", s
+	}
+	parts := strings.SplitN(s, " ", 2)
+	asn = strings.ToUpper(parts[0])
+	if len(parts) == 2 {
+		org = strings.TrimSpace(parts[1])
+	}
+	return asn, org
+}
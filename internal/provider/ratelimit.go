@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"github.com/ODIN7h3C0d3r/Netra/internal/network"
+)
+
+// rateLimitedProvider takes a token from a shared limiter before every lookup
+// and pauses the limiter when the API answers 429
+type rateLimitedProvider struct {
+	Provider
+	limiter *network.RateLimiter
+	wait    bool
+}
+
+// NewRateLimited wraps p so lookups respect limiter. With wait set, a lookup
+// blocks until a token is available; otherwise it fails immediately with a
+// *network.RateLimitError so a chain can fall through to the next provider.
+func NewRateLimited(p Provider, limiter *network.RateLimiter, wait bool) Provider {
+	return &rateLimitedProvider{Provider: p, limiter: limiter, wait: wait}
+}
+
+// retryKey marks the context of a lookup that retries a failed one
+type retryKey struct{}
+
+// WithRetry marks ctx as a retry of a failed lookup. Rate limited providers
+// let retries through without waiting for a token, unless a 429 paused them.
+func WithRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryKey{}, true)
+}
+
+func (r *rateLimitedProvider) Lookup(ctx context.Context, ip string) (*formatter.IPInfo, error) {
+	wait, allow := r.limiter.Wait, r.limiter.Allow
+	if retry, _ := ctx.Value(retryKey{}).(bool); retry {
+		wait, allow = r.limiter.WaitRetry, r.limiter.AllowRetry
+	}
+	if r.wait {
+		if err := wait(ctx); err != nil {
+			return nil, err
+		}
+	} else if d, ok := allow(); !ok {
+		return nil, &network.RateLimitError{RetryAfter: d}
+	}
+
+	info, err := r.Provider.Lookup(ctx, ip)
+
+	var rateErr *network.RateLimitError
+	if errors.As(err, &rateErr) {
+		r.limiter.Pause(rateErr.RetryAfter)
+	}
+	return info, err
+}
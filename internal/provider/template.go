@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"github.com/ODIN7h3C0d3r/Netra/internal/network"
+	"github.com/ODIN7h3C0d3r/Netra/internal/util"
+)
+
+// templateProvider queries any JSON API described entirely by config:
+// a URL pattern and a mapping from IPInfo fields to JSON paths
+type templateProvider struct {
+	name   string
+	url    string
+	token  string
+	fields map[string]string
+	client network.HTTPClient
+}
+
+func newTemplate(name string, s Settings, client network.HTTPClient) (Provider, error) {
+	if s.URL == "" {
+		return nil, fmt.Errorf("provider %s: template providers need a url", name)
+	}
+	if !strings.Contains(s.URL, "{ip}") {
+		return nil, fmt.Errorf("provider %s: url must contain the {ip} placeholder", name)
+	}
+	if len(s.Fields) == 0 {
+		return nil, fmt.Errorf("provider %s: template providers need a fields mapping", name)
+	}
+
+	probe := &formatter.IPInfo{}
+	for field := range s.Fields {
+		if err := probe.SetField(field, nil); err != nil {
+			return nil, fmt.Errorf("provider %s: %v", name, err)
+		}
+	}
+
+	return &templateProvider{name: name, url: s.URL, token: s.Token, fields: s.Fields, client: client}, nil
+}
+
+func (p *templateProvider) Name() string { return p.name }
+
+func (p *templateProvider) Capabilities() Capabilities {
+	_, asn := p.fields["asn"]
+	_, proxy := p.fields["is_proxy"]
+	_, mobile := p.fields["is_mobile"]
+	_, hosting := p.fields["is_hosting"]
+	return Capabilities{
+		ASN:           asn,
+		Proxy:         proxy,
+		Mobile:        mobile,
+		Hosting:       hosting,
+		RequiresToken: strings.Contains(p.url, "{token}"),
+	}
+}
+
+func (p *templateProvider) Lookup(ctx context.Context, ip string) (*formatter.IPInfo, error) {
+	endpoint := strings.NewReplacer(
+		"{ip}", url.PathEscape(ip),
+		"{token}", url.QueryEscape(p.token),
+	).Replace(p.url)
+
+	body, err := network.FetchJSON(ctx, p.client, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s response: %v", p.name, err)
+	}
+
+	info := &formatter.IPInfo{IP: ip}
+	for field, path := range p.fields {
+		value, ok := util.LookupPath(doc, path)
+		if !ok {
+			continue
+		}
+		if err := info.SetField(field, value); err != nil {
+			return nil, fmt.Errorf("%s: %v", p.name, err)
+		}
+	}
+
+	if info.Country == "" && info.CountryCode != "" {
+		info.Country = countryName(info.CountryCode)
+	}
+	info.Continent = continentName(info.Continent)
+	info.Provider = p.name
+	info.Normalize()
+	return info, nil
+}
@@ -3,6 +3,7 @@ package util
 import (
     "fmt"
     "os"
+    "strconv"
     "strings"
 )
 
@@ -16,6 +17,7 @@ const (
 
 var (
     quietMode bool
+    noColor   bool
 )
 
 // SetQuiet enables/disables all output
@@ -23,13 +25,51 @@ func SetQuiet(q bool) {
     quietMode = q
 }
 
+// SetColorTheme selects the log color theme ("dark", "light" or "none")
+func SetColorTheme(theme string) {
+    noColor = theme == "none"
+}
+
+// ColorsEnabled reports whether output may use ANSI colors: the color theme
+// is not "none" and NO_COLOR is not set
+func ColorsEnabled() bool {
+    return !noColor && os.Getenv("NO_COLOR") == ""
+}
+
+// TerminalWidth returns the width of the terminal behind f in columns:
+// $COLUMNS if set, else the size the terminal reports, else 0
+func TerminalWidth(f *os.File) int {
+    if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+        return n
+    }
+    return terminalSize(f)
+}
+
+// colorize wraps text in an ANSI color unless colors are disabled
+func colorize(color, text string) string {
+    if noColor {
+        return text
+    }
+    return color + text + colorReset
+}
+
 // LogInfo prints a formatted info message
 func LogInfo(format string, args ...interface{}) {
     if quietMode {
         return
     }
     msg := fmt.Sprintf(format, args...)
-    fmt.Fprintf(os.Stdout, "%s[INFO]%s %s\n", colorBlue, colorReset, msg)
+    fmt.Fprintf(os.Stdout, "%s %s\n", colorize(colorBlue, "[INFO]"), msg)
+}
+
+// LogStatus prints a formatted info message to stderr, so it never
+// interleaves with results streamed to stdout
+func LogStatus(format string, args ...interface{}) {
+    if quietMode {
+        return
+    }
+    msg := fmt.Sprintf(format, args...)
+    fmt.Fprintf(os.Stderr, "%s %s\n", colorize(colorBlue, "[INFO]"), msg)
 }
 
 // LogWarning prints a formatted warning message
@@ -38,7 +78,7 @@ func LogWarning(format string, args ...interface{}) {
         return
     }
     msg := fmt.Sprintf(format, args...)
-    fmt.Fprintf(os.Stderr, "%s[WARN]%s %s\n", colorYellow, colorReset, msg)
+    fmt.Fprintf(os.Stderr, "%s %s\n", colorize(colorYellow, "[WARN]"), msg)
 }
 
 // LogError prints a formatted error message
@@ -47,7 +87,7 @@ func LogError(format string, args ...interface{}) {
         return
     }
     msg := fmt.Sprintf(format, args...)
-    fmt.Fprintf(os.Stderr, "%s[ERROR]%s %s\n", colorRed, colorReset, msg)
+    fmt.Fprintf(os.Stderr, "%s %s\n", colorize(colorRed, "[ERROR]"), msg)
 }
 
 // PrintBanner prints a stylized ASCII banner
@@ -57,6 +97,6 @@ func PrintBanner(text string) {
     }
     lines := strings.Split(text, "\n")
     for _, line := range lines {
-        fmt.Fprintf(os.Stdout, "%s%s%s\n", colorGreen, line, colorReset)
+        fmt.Fprintln(os.Stdout, colorize(colorGreen, line))
     }
 }
\ No newline at end of file
@@ -0,0 +1,26 @@
+//go:build windows
+
+package util
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var procGetConsoleScreenBufferInfo = syscall.NewLazyDLL("kernel32.dll").NewProc("GetConsoleScreenBufferInfo")
+
+// terminalSize asks the console behind f for its width in columns
+func terminalSize(f *os.File) int {
+	var info struct {
+		size, cursor             [2]int16
+		attributes               uint16
+		left, top, right, bottom int16
+		maxWidth, maxHeight      int16
+	}
+	ok, _, _ := procGetConsoleScreenBufferInfo.Call(f.Fd(), uintptr(unsafe.Pointer(&info)))
+	if ok == 0 {
+		return 0
+	}
+	return int(info.right-info.left) + 1
+}
@@ -1,10 +1,14 @@
 package util
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 )
 
@@ -25,13 +29,22 @@ func ReadLines(path string) ([]string, error) {
 	return result, nil
 }
 
-// SaveToFile writes content to a file
-func SaveToFile(path, content string) error {
-	err := os.MkdirAll(filepath.Dir(path), 0755)
-	if err != nil {
-		return err
+// CreateFile creates (or truncates) a file for incremental writing,
+// creating its parent directories first
+func CreateFile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
 	}
-	return ioutil.WriteFile(path, []byte(content), 0644)
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+}
+
+// AppendFile opens a file for appending, creating it and its parent
+// directories if needed
+func AppendFile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 }
 
 // Truncate returns a truncated string with ellipsis if needed
@@ -75,3 +88,100 @@ func ExpandHome(path string) string {
 func IsEmpty(s string) bool {
 	return strings.TrimSpace(s) == ""
 }
+
+// ParseDuration extends time.ParseDuration with day ("7d") and week ("2w") units
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+
+	if unit == 0 {
+		return time.ParseDuration(s)
+	}
+
+	n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return time.Duration(n * float64(unit)), nil
+}
+
+// HumanBytes formats a byte count with a binary unit (e.g. "1.5 KiB")
+func HumanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// LookupPath walks a decoded JSON document using a dotted path such as
+// "location.lat" or "data.0.country"
+func LookupPath(doc interface{}, path string) (interface{}, bool) {
+	cur := doc
+	for _, part := range strings.Split(path, ".") {
+		switch node := cur.(type) {
+		case map[string]interface{}:
+			v, ok := node[part]
+			if !ok {
+				return nil, false
+			}
+			cur = v
+		case []interface{}:
+			var idx int
+			if _, err := fmt.Sscanf(part, "%d", &idx); err != nil || idx < 0 || idx >= len(node) {
+				return nil, false
+			}
+			cur = node[idx]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// NormalizeJSONPath turns JSONPath-style selectors ("$.a.b", "$['a'].b[0]")
+// into the dotted form of LookupPath ("a.b.0")
+func NormalizeJSONPath(path string) string {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.NewReplacer("['", ".", "']", "", `["`, ".", `"]`, "", "[", ".", "]", "").Replace(path)
+	return strings.TrimPrefix(path, ".")
+}
+
+// TopCounts formats the n most frequent keys of counts as "key (count)" pairs,
+// most frequent first, e.g. "/login (120), /admin (4)"
+func TopCounts(counts map[string]int, n int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if n > 0 && len(keys) > n {
+		keys = keys[:n]
+	}
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s (%d)", k, counts[k])
+	}
+	return strings.Join(parts, ", ")
+}
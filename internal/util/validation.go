@@ -2,16 +2,80 @@ package util
 
 import (
     "fmt"
-    "net"
+    "net/netip"
     "net/url"
     "os"
     "regexp"
     "strings"
+
+    "github.com/ODIN7h3C0d3r/Netra/internal/iana"
 )
 
-// IsValidIP validates an IPv4 or IPv6 address
+// IsValidIP validates an IPv4 or IPv6 address in any form ParseIP accepts
 func IsValidIP(ip string) bool {
-    return net.ParseIP(ip) != nil
+    _, err := ParseIP(ip, false)
+    return err == nil
+}
+
+// ParseIP parses an IPv4 or IPv6 address leniently: surrounding brackets and
+// an IPv6 zone ("%eth0") are dropped, and leading zeros in IPv4 octets are
+// stripped and read as decimal, never octal ("008.008.008.008" is 8.8.8.8).
+// With unmap, IPv4-mapped IPv6 addresses become plain IPv4.
+func ParseIP(s string, unmap bool) (netip.Addr, error) {
+    s = strings.TrimSpace(s)
+    if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+        s = s[1 : len(s)-1]
+    }
+    if i := strings.IndexByte(s, '%'); i >= 0 && strings.Contains(s[:i], ":") {
+        s = s[:i]
+    }
+
+    // Dotted quads may stand alone or end an IPv6 address (::ffff:1.2.3.4)
+    if i := strings.LastIndexByte(s, ':') + 1; strings.Contains(s[i:], ".") {
+        quad, ok := stripOctetZeros(s[i:])
+        if !ok {
+            return netip.Addr{}, fmt.Errorf("invalid IP address: %s", s)
+        }
+        s = s[:i] + quad
+    }
+
+    addr, err := netip.ParseAddr(s)
+    if err != nil {
+        return netip.Addr{}, fmt.Errorf("invalid IP address: %s", s)
+    }
+    if unmap {
+        addr = addr.Unmap()
+    }
+    return addr, nil
+}
+
+// CanonicalIP returns the canonical text form of an address: dotted decimal
+// for IPv4 and RFC 5952 (lower case, longest zero run compressed) for IPv6,
+// without a zone
+func CanonicalIP(s string, unmap bool) (string, error) {
+    addr, err := ParseIP(s, unmap)
+    if err != nil {
+        return "", err
+    }
+    return addr.String(), nil
+}
+
+// stripOctetZeros removes leading zeros from each octet of a dotted quad
+func stripOctetZeros(s string) (string, bool) {
+    octets := strings.Split(s, ".")
+    if len(octets) != 4 {
+        return "", false
+    }
+    for i, o := range octets {
+        if o == "" || strings.Trim(o, "0123456789") != "" {
+            return "", false
+        }
+        if o = strings.TrimLeft(o, "0"); o == "" {
+            o = "0"
+        }
+        octets[i] = o
+    }
+    return strings.Join(octets, "."), true
 }
 
 // IsValidHostname validates a domain name or hostname
@@ -49,38 +113,74 @@ func DirExists(path string) bool {
     return err == nil && info.IsDir()
 }
 
-// IsValidFormat checks if the output format is supported
-func IsValidFormat(format string) bool {
-    switch strings.ToLower(format) {
-    case "text", "json", "csv", "yaml":
-        return true
-    default:
-        return false
-    }
+// IsPrivateIP reports whether the IP falls in a special-purpose block that
+// is not globally reachable (private, loopback, link-local, CGNAT,
+// documentation, multicast or reserved; see the iana package)
+func IsPrivateIP(ipStr string) bool {
+    block, ok := iana.Lookup(ipStr)
+    return ok && !block.Global
 }
 
-// IsPrivateIP checks if the IP is private (RFC 1918 and RFC 4193)
-func IsPrivateIP(ipStr string) bool {
-    ip := net.ParseIP(ipStr)
-    if ip == nil {
-        return false
+// IPv6 transition mechanisms that embed an IPv4 address
+const (
+    TransitionIPv4Mapped = "ipv4-mapped"
+    Transition6to4       = "6to4"
+    TransitionTeredo     = "teredo"
+    TransitionNAT64      = "nat64"
+)
+
+// Transition is the IPv4 address embedded in an IPv6 transition address
+type Transition struct {
+    Mechanism string
+    IPv4      string
+
+    // Teredo only: the Teredo server and the client's public (NAT) port,
+    // de-obfuscated; IPv4 is the client's public address
+    Server string
+    Port   int
+}
+
+// TransitionPrefixes are the IPv6 blocks whose addresses DecodeTransition
+// recognises
+var TransitionPrefixes = []netip.Prefix{
+    netip.MustParsePrefix("::ffff:0:0/96"),
+    netip.MustParsePrefix("2002::/16"),
+    netip.MustParsePrefix("2001::/32"),
+    netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// DecodeTransition extracts the IPv4 address embedded in an IPv4-mapped
+// (::ffff:0:0/96), 6to4 (2002::/16), Teredo (2001::/32, RFC 4380) or
+// well-known NAT64 (64:ff9b::/96, RFC 6052) address
+func DecodeTransition(ip string) (Transition, bool) {
+    addr, err := ParseIP(ip, false)
+    if err != nil || !addr.Is6() {
+        return Transition{}, false
     }
-    privateRanges := []string{
-        "0.0.0.0/8",
-        "10.0.0.0/8",
-        "172.16.0.0/12",
-        "192.168.0.0/16",
-        "100.64.0.0/10",
-        "::1/128",
-        "fc00::/7",
-    }
-    for _, cidr := range privateRanges {
-        _, block, _ := net.ParseCIDR(cidr)
-        if block.Contains(ip) {
-            return true
-        }
+    b := addr.As16()
+    v4 := func(p []byte) string {
+        return netip.AddrFrom4([4]byte{p[0], p[1], p[2], p[3]}).String()
+    }
+
+    switch {
+    case addr.Is4In6():
+        return Transition{Mechanism: TransitionIPv4Mapped, IPv4: v4(b[12:])}, true
+    case b[0] == 0x20 && b[1] == 0x02:
+        return Transition{Mechanism: Transition6to4, IPv4: v4(b[2:])}, true
+    case b[0] == 0x20 && b[1] == 0x01 && b[2] == 0 && b[3] == 0:
+        // The client address and port are stored inverted so NATs do not
+        // rewrite them
+        client := [4]byte{^b[12], ^b[13], ^b[14], ^b[15]}
+        return Transition{
+            Mechanism: TransitionTeredo,
+            IPv4:      v4(client[:]),
+            Server:    v4(b[4:]),
+            Port:      int(^b[10])<<8 | int(^b[11]),
+        }, true
+    case netip.MustParsePrefix("64:ff9b::/96").Contains(addr):
+        return Transition{Mechanism: TransitionNAT64, IPv4: v4(b[12:])}, true
     }
-    return false
+    return Transition{}, false
 }
 
 // ValidateIPList validates a list of IPs and returns valid ones
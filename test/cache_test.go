@@ -0,0 +1,145 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/core"
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+)
+
+func TestDiskCachePersistsBetweenRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "netra", "cache.jsonl")
+
+	first, err := core.OpenDiskCache(path, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to open cache: %v", err)
+	}
+	first.Set("8.8.8.8", &formatter.IPInfo{IP: "8.8.8.8", Country: "United States"})
+	if err := first.Close(); err != nil {
+		t.Fatalf("Failed to close cache: %v", err)
+	}
+
+	second, err := core.OpenDiskCache(path, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to reopen cache: %v", err)
+	}
+	defer second.Close()
+
+	info, ok := second.Get("8.8.8.8")
+	if !ok || info.Country != "United States" {
+		t.Errorf("Expected cached entry after reopening, got %v (found %v)", info, ok)
+	}
+}
+
+func TestDiskCacheSharedBetweenProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+
+	a, err := core.OpenDiskCache(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := core.OpenDiskCache(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a.Set("1.1.1.1", &formatter.IPInfo{IP: "1.1.1.1"})
+	b.Set("9.9.9.9", &formatter.IPInfo{IP: "9.9.9.9"})
+
+	// b replays a's record before appending its own
+	if _, ok := b.Get("1.1.1.1"); !ok {
+		t.Error("Expected entry written by another cache instance to be visible")
+	}
+
+	// a compacts the file; b must notice and reload rather than lose entries
+	a.Sweep()
+	b.Set("4.4.4.4", &formatter.IPInfo{IP: "4.4.4.4"})
+	for _, ip := range []string{"1.1.1.1", "9.9.9.9", "4.4.4.4"} {
+		if _, ok := b.Get(ip); !ok {
+			t.Errorf("Expected %s after compaction by another instance", ip)
+		}
+	}
+	a.Close()
+	b.Close()
+}
+
+func TestDiskCacheExpiresAndSkipsCorruptLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+	stale := time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339Nano)
+	data := `{"ip":"1.2.3.4","info":{"ip":"1.2.3.4"},"stored":"` + stale + `"}` + "\n" +
+		"not json\n" +
+		`{"ip":"5.6.7.8","info":{"ip":"5.6.7.8"},"stored":"` + time.Now().UTC().Format(time.RFC3339Nano) + `"}` + "\n" +
+		`{"ip":"9.9.9`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := core.OpenDiskCache(path, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to open cache: %v", err)
+	}
+	if _, ok := c.Get("1.2.3.4"); ok {
+		t.Error("Expected entry older than the TTL to be expired")
+	}
+	if _, ok := c.Get("5.6.7.8"); !ok {
+		t.Error("Expected fresh entry after a corrupt line")
+	}
+
+	// Appending after a truncated line must not merge into it
+	c.Set("2.2.2.2", &formatter.IPInfo{IP: "2.2.2.2"})
+	c.Close()
+
+	reopened, err := core.OpenDiskCache(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if _, ok := reopened.Get("2.2.2.2"); !ok {
+		t.Error("Expected entry appended after a truncated line to survive")
+	}
+}
+
+func TestDiskCacheImportAndPurge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+	c, err := core.OpenDiskCache(path, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	old := time.Now().Add(-48 * time.Hour)
+	err = c.Import([]core.CacheItem{
+		{IP: "8.8.8.8", Info: &formatter.IPInfo{IP: "8.8.8.8"}, Stored: old},
+		{IP: "10.0.0.1", Info: &formatter.IPInfo{IP: "10.0.0.1"}},
+	})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Entries != 2 || stats.Expired != 1 {
+		t.Errorf("Expected 2 entries with 1 expired, got %+v", stats)
+	}
+	if !stats.Oldest.Equal(old.UTC()) {
+		t.Errorf("Expected imported store time to be kept, got %v", stats.Oldest)
+	}
+
+	removed, err := c.Purge(func(item core.CacheItem) bool { return item.Expired })
+	if err != nil || removed != 1 {
+		t.Fatalf("Expected 1 entry purged, got %d (%v)", removed, err)
+	}
+
+	items, err := c.Items()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].IP != "10.0.0.1" {
+		t.Errorf("Expected only 10.0.0.1 to remain, got %+v", items)
+	}
+}
@@ -1,12 +1,17 @@
 package test
 
 import (
+	"encoding/json"
+	"errors"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func binaryPath() string {
@@ -26,6 +31,12 @@ func TestNetraHelp(t *testing.T) {
 	if !strings.Contains(string(output), "Usage: netra") {
 		t.Errorf("Help output missing expected usage text. Output: %s", output)
 	}
+	if !strings.Contains(string(output), "replacing it (jsonl, template, yaml)") {
+		t.Errorf("Help output should list the appendable formats. Output: %s", output)
+	}
+	if strings.Contains(string(output), " .\n") || !strings.Contains(string(output), "  table    text/plain\n") {
+		t.Errorf("Help output should skip the extension of formats without one. Output: %s", output)
+	}
 }
 
 func TestNetraVersionFlag(t *testing.T) {
@@ -81,3 +92,241 @@ func TestNetraOutputFile(t *testing.T) {
 	}
 	os.Remove(outputFile)
 }
+
+// csvHeader runs netra with a config that never reaches a provider (the
+// addresses are private) and returns the CSV header it wrote
+func csvHeader(t *testing.T, stdin string, args ...string) []string {
+	t.Helper()
+	cfg := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(cfg, []byte(`{"cache": {"enabled": false}, "network": {"dns_servers": []}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(binaryPath(), append([]string{"-config", cfg, "-quiet", "-format", "csv"}, args...)...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("netra %v failed: %v\n%s", args, err, output)
+	}
+	return strings.Split(strings.SplitN(string(output), "\n", 2)[0], ",")
+}
+
+func TestNetraColumnsDoNotDependOnInputOrder(t *testing.T) {
+	entries := []string{"10.0.0.1", "http://10.0.0.2/", "::ffff:10.0.0.3", "010.0.0.4"}
+	file := filepath.Join(t.TempDir(), "ips.txt")
+	if err := os.WriteFile(file, []byte(strings.Join(entries, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	runs := map[string][]string{
+		"arguments": csvHeader(t, "", entries...),
+		"file":      csvHeader(t, "", "-file", file),
+		"pipe":      csvHeader(t, strings.Join(entries, "\n")),
+	}
+	for name, header := range runs {
+		for _, column := range []string{"resolved_from", "transition", "embedded_ipv4", "original"} {
+			if !strings.Contains(strings.Join(header, ","), column) {
+				t.Errorf("%s: expected a %s column for a later entry, got %v", name, column, header)
+			}
+		}
+	}
+}
+
+func TestNetraPipedTextTakesColumnsFromFirstResult(t *testing.T) {
+	cfg := filepath.Join(t.TempDir(), "config.json")
+	writeConfig(t, cfg, "")
+
+	cases := map[string]bool{"10.0.0.1": false, "http://10.0.0.2/": true}
+	for stdin, resolved := range cases {
+		cmd := exec.Command(binaryPath(), "-config", cfg, "-quiet", "-format", "text")
+		cmd.Stdin = strings.NewReader(stdin)
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("%s: netra failed: %v", stdin, err)
+		}
+		if strings.Contains(string(output), "Transition") || strings.Contains(string(output), "Original") {
+			t.Errorf("%s: unexpected empty optional fields: %s", stdin, output)
+		}
+		if strings.Contains(string(output), "Resolved_from") != resolved {
+			t.Errorf("%s: expected resolved_from=%v, got: %s", stdin, resolved, output)
+		}
+	}
+}
+
+// runNetra runs netra in dir with extra environment variables and returns
+// what it wrote to stdout and stderr
+func runNetra(t *testing.T, dir string, env []string, args ...string) (string, string, error) {
+	t.Helper()
+	bin, err := filepath.Abs(binaryPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(bin, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	var stdout, stderr strings.Builder
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err = cmd.Run()
+	return stdout.String(), stderr.String(), err
+}
+
+// writeConfig writes a config that never reaches a provider or a DNS server,
+// with the given JSON members added
+func writeConfig(t *testing.T, path, extra string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := `{"cache": {"enabled": false}, "network": {"dns_servers": []}` + extra + `}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNetraConfigSearchOrder(t *testing.T) {
+	work, xdg, home := t.TempDir(), t.TempDir(), t.TempDir()
+	local := filepath.Join(work, "config", "config.json")
+	user := filepath.Join(xdg, "netra", "config.json")
+	explicit := filepath.Join(t.TempDir(), "explicit.json")
+	for _, path := range []string{local, user, explicit} {
+		writeConfig(t, path, "")
+	}
+
+	cases := []struct {
+		name string
+		env  []string
+		args []string
+		want string
+	}{
+		{"explicit", []string{"XDG_CONFIG_HOME=" + xdg}, []string{"-config", explicit}, explicit},
+		{"xdg", []string{"XDG_CONFIG_HOME=" + xdg}, nil, user},
+		{"local", []string{"XDG_CONFIG_HOME=" + t.TempDir(), "HOME=" + home}, nil, filepath.Join("config", "config.json")},
+	}
+	for _, c := range cases {
+		stdout, stderr, err := runNetra(t, work, c.env, append(c.args, "10.0.0.1")...)
+		if err != nil {
+			t.Fatalf("%s: netra failed: %v\n%s", c.name, err, stderr)
+		}
+		if !strings.Contains(stderr, "Loaded config from "+c.want+"\n") {
+			t.Errorf("%s: expected %s to be loaded, got: %s", c.name, c.want, stderr)
+		}
+		if strings.Contains(stdout, "Loaded config") {
+			t.Errorf("%s: config message written to stdout: %s", c.name, stdout)
+		}
+	}
+
+	if _, stderr, err := runNetra(t, work, nil, "-config", filepath.Join(work, "missing.json"), "10.0.0.1"); err == nil || !strings.Contains(stderr, "config file not found") {
+		t.Errorf("Expected a missing -config file to fail, got %v: %s", err, stderr)
+	}
+}
+
+func TestNetraFlagsOverrideConfig(t *testing.T) {
+	cfg := filepath.Join(t.TempDir(), "config.json")
+	writeConfig(t, cfg, `, "format": {"default": "csv"}, "ui": {"quiet_mode": true}`)
+
+	stdout, stderr, err := runNetra(t, ".", nil, "-config", cfg, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("netra failed: %v\n%s", err, stderr)
+	}
+	if !strings.HasPrefix(stdout, "ip,country,") || stderr != "" {
+		t.Errorf("Expected quiet csv output from the config, got stdout %q, stderr %q", stdout, stderr)
+	}
+
+	stdout, stderr, err = runNetra(t, ".", nil, "-config", cfg, "-format", "jsonl", "-quiet=false", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("netra failed: %v\n%s", err, stderr)
+	}
+	if !strings.Contains(stdout, `"ip":"10.0.0.1"`) || strings.Contains(stdout, "ip,country,") {
+		t.Errorf("Expected -format to override format.default, got %q", stdout)
+	}
+	if !strings.Contains(stderr, "Loaded config from") {
+		t.Errorf("Expected -quiet=false to override ui.quiet_mode, got stderr %q", stderr)
+	}
+}
+
+func TestNetraConfigValidation(t *testing.T) {
+	cases := map[string]string{
+		`, "api": {"timeout": "soon"}`:                `api.timeout: invalid duration "soon"`,
+		`, "cache": {"enabled": true, "ttl": "1x"}`:   `cache.ttl: invalid duration "1x"`,
+		`, "api": {"base_url": "ftp://example.com"}`:  `api.base_url: URL must use http or https`,
+		`, "api": {"base_url": "not a url"}`:          `api.base_url: invalid URL`,
+		`, "network": {"proxy": "gopher://proxy:70"}`: `network.proxy: unsupported scheme "gopher"`,
+	}
+	for extra, want := range cases {
+		cfg := filepath.Join(t.TempDir(), "config.json")
+		writeConfig(t, cfg, extra)
+		_, stderr, err := runNetra(t, ".", nil, "-config", cfg, "10.0.0.1")
+		if err == nil || !strings.Contains(stderr, want) {
+			t.Errorf("%s: expected %q, got %v: %s", extra, want, err, stderr)
+		}
+	}
+}
+
+func TestNetraDeadlineFlushesPartialResults(t *testing.T) {
+	dir := t.TempDir()
+	cfg := filepath.Join(dir, "config.json")
+	out := filepath.Join(dir, "results.jsonl")
+	// Nothing listens on port 1, so 8.8.8.8 is still retrying at the deadline
+	writeConfig(t, cfg, `, "api": {"base_url": "http://127.0.0.1:1"}`)
+
+	start := time.Now()
+	_, stderr, err := runNetra(t, dir, nil, "-config", cfg, "-quiet", "-deadline", "1s", "-output", out, "10.0.0.1", "8.8.8.8")
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 124 {
+		t.Fatalf("Expected exit code 124 at the deadline, got %v: %s", err, stderr)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the run to stop at the deadline, took %v", elapsed)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("Expected the partial results to be saved: %v", err)
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal(data, &record); err != nil || record["ip"] != "10.0.0.1" {
+		t.Errorf("Expected the finished 10.0.0.1 record, got %q (%v)", data, err)
+	}
+}
+
+func TestNetraStatusMessagesStayOffStdout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"country": "Japan"}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := filepath.Join(dir, "config.json")
+	data := `{"network": {"dns_servers": []}, "providers": {"default": "stub", "backends": {"stub": {"type": "template", "url": "` + server.URL + `/{ip}", "fields": {"country": "country"}}}}}`
+	if err := os.WriteFile(cfg, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	env := []string{"XDG_CACHE_HOME=" + dir}
+
+	for run := 0; run < 2; run++ {
+		stdout, stderr, err := runNetra(t, dir, env, "-config", cfg, "-format", "jsonl", "8.8.8.8")
+		if err != nil {
+			t.Fatalf("netra failed: %v\n%s", err, stderr)
+		}
+		if strings.Contains(stdout, "[INFO]") {
+			t.Errorf("Run %d: status message written to stdout: %s", run+1, stdout)
+		}
+		if run == 1 && !strings.Contains(stderr, "Using cached result for 8.8.8.8") {
+			t.Errorf("Expected the second run to use the cache, got stderr: %s", stderr)
+		}
+	}
+}
+
+func TestNetraReportsEntriesAboveMaxExpand(t *testing.T) {
+	cfg := filepath.Join(t.TempDir(), "config.json")
+	writeConfig(t, cfg, "")
+
+	_, stderr, err := runNetra(t, ".", nil, "-config", cfg, "-quiet", "8.0.0.0/8")
+	if err == nil {
+		t.Fatal("Expected a run with only an oversized range to fail")
+	}
+	if !strings.Contains(stderr, "8.0.0.0/8 expands to 16777216 addresses") || strings.Contains(stderr, "No IP addresses provided") {
+		t.Errorf("Expected the -max-expand limit to be reported, got: %s", stderr)
+	}
+}
@@ -0,0 +1,359 @@
+package test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"gopkg.in/yaml.v2"
+)
+
+func TestStreamWritersProduceValidDocuments(t *testing.T) {
+	records := []*formatter.IPInfo{
+		{IP: "8.8.8.8", Country: "United States", ASN: "AS15169"},
+		{IP: "1.1.1.1", Country: "Australia", ASN: "AS13335"},
+	}
+
+	for _, format := range []string{"json", "yaml", "csv"} {
+		for _, n := range []int{0, 1, 2} {
+			var buf bytes.Buffer
+			sw, err := formatter.NewStreamWriter(&buf, format, "ip,country,asn")
+			if err != nil {
+				t.Fatalf("%s: %v", format, err)
+			}
+			if err := sw.Begin(); err != nil {
+				t.Fatalf("%s: Begin: %v", format, err)
+			}
+			for _, r := range records[:n] {
+				if err := sw.Write(r); err != nil {
+					t.Fatalf("%s: Write: %v", format, err)
+				}
+			}
+			if err := sw.End(); err != nil {
+				t.Fatalf("%s: End: %v", format, err)
+			}
+
+			var got int
+			switch format {
+			case "json":
+				var rows []map[string]interface{}
+				err = json.Unmarshal(buf.Bytes(), &rows)
+				got = len(rows)
+			case "yaml":
+				var rows []map[string]interface{}
+				err = yaml.Unmarshal(buf.Bytes(), &rows)
+				got = len(rows)
+			case "csv":
+				var rows [][]string
+				rows, err = csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+				got = len(rows) - 1
+			}
+			if err != nil || got != n {
+				t.Errorf("%s with %d records: parsed %d rows (%v) from %q", format, n, got, err, buf.String())
+			}
+		}
+	}
+}
+
+func TestFormatMatchesStreamOutput(t *testing.T) {
+	data := []*formatter.IPInfo{{IP: "9.9.9.9", Country: "Switzerland"}}
+
+	out, err := formatter.Format(data, "json", "ip,country")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[\n  {\n    \"country\": \"Switzerland\",\n    \"ip\": \"9.9.9.9\"\n  }\n]"
+	if out != want {
+		t.Errorf("Unexpected JSON:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestPlusFieldsExtendDefaults(t *testing.T) {
+	records := []*formatter.IPInfo{{IP: "93.184.216.34", Country: "United States", ResolvedFrom: "https://example.com/"}}
+
+	out, err := formatter.FormatCSV(records, "+resolved_from")
+	if err != nil {
+		t.Fatalf("FormatCSV failed: %v", err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	header := rows[0]
+	if header[0] != "ip" || header[len(header)-1] != "resolved_from" {
+		t.Fatalf("unexpected header %v", header)
+	}
+	if got := rows[1][len(header)-1]; got != "https://example.com/" {
+		t.Errorf("resolved_from = %q, want the original URL", got)
+	}
+}
+
+func TestInputColumnsPassThrough(t *testing.T) {
+	records := []*formatter.IPInfo{{
+		IP:      "192.0.2.7",
+		Country: "Japan",
+		Input:   []formatter.Column{{Name: "user", Value: "alice"}, {Name: "country", Value: "US"}},
+	}}
+
+	out, err := formatter.FormatCSV(records, "+input")
+	if err != nil {
+		t.Fatalf("FormatCSV failed: %v", err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if got := strings.Join(rows[0][:4], ","); got != "user,input_country,ip,country" {
+		t.Errorf("header starts %q, want input columns first with clashes prefixed", got)
+	}
+	if rows[1][0] != "alice" || rows[1][1] != "US" || rows[1][3] != "Japan" {
+		t.Errorf("unexpected row %v", rows[1])
+	}
+}
+
+func TestFormatRegistry(t *testing.T) {
+	for _, name := range []string{"text", "json", "csv", "yaml"} {
+		f, ok := formatter.Lookup(name)
+		if !ok || f.Name() != name || f.MIMEType() == "" || len(f.Extensions()) == 0 {
+			t.Errorf("%s: not registered with its metadata: %v", name, f)
+		}
+	}
+	if f, ok := formatter.Lookup(""); !ok || f.Name() != formatter.DefaultFormat {
+		t.Errorf("Expected the empty name to select the default format")
+	}
+
+	paths := map[string]string{
+		"results.csv":       "csv",
+		"out/RESULTS.JSON":  "json",
+		"report.yml":        "yaml",
+		"/tmp/lookups.yaml": "yaml",
+	}
+	for path, want := range paths {
+		if f, ok := formatter.ForPath(path); !ok || f.Name() != want {
+			t.Errorf("ForPath(%q): got %v, want %s", path, f, want)
+		}
+	}
+	for _, path := range []string{"results", "results.xlsx", ""} {
+		if f, ok := formatter.ForPath(path); ok {
+			t.Errorf("ForPath(%q): unexpected format %s", path, f.Name())
+		}
+	}
+
+	if _, err := formatter.NewStreamWriter(&bytes.Buffer{}, "xml", ""); err == nil || !strings.Contains(err.Error(), "available: "+strings.Join(formatter.Names(), ", ")) {
+		t.Errorf("Expected the unknown format error to list the formats, got %v", err)
+	}
+}
+
+func TestJSONLWritesOneObjectPerLine(t *testing.T) {
+	var buf bytes.Buffer
+	sw, err := formatter.NewStreamWriter(&buf, "jsonl", "ip,country")
+	if err != nil {
+		t.Fatalf("NewStreamWriter failed: %v", err)
+	}
+	ew, ok := sw.(formatter.ErrorWriter)
+	if !ok {
+		t.Fatal("Expected the jsonl writer to record failed lookups")
+	}
+
+	sw.Begin()
+	sw.Write(&formatter.IPInfo{IP: "8.8.8.8", Country: "United States", ASN: "AS15169"})
+	ew.WriteError(&formatter.IPInfo{IP: "1.1.1.1", Line: 3}, errors.New("timeout"))
+	sw.End()
+
+	want := `{"country":"United States","ip":"8.8.8.8"}` + "\n" +
+		`{"error":"timeout","ip":"1.1.1.1","line":3}` + "\n"
+	if buf.String() != want {
+		t.Errorf("Unexpected JSON Lines output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	// Consensus fields only appear on consensus results
+	out, err := formatter.FormatJSONL([]*formatter.IPInfo{{IP: "8.8.8.8"}}, "")
+	if err != nil || strings.Contains(out, "sources") || strings.Contains(out, "disagreements") {
+		t.Errorf("Expected no consensus fields outside consensus mode, got %s (%v)", out, err)
+	}
+}
+
+func TestTableFitsWidthAndSorts(t *testing.T) {
+	records := []*formatter.IPInfo{
+		{IP: "9.9.9.9", Country: "Switzerland", ISP: "Quad9 Foundation with a very long name", IsProxy: true},
+		{IP: "10.0.0.1", AddressType: "private"},
+		{IP: "1.1.1.1", Country: "Australia", ISP: "Cloudflare, Inc."},
+	}
+
+	var buf bytes.Buffer
+	sw, err := formatter.NewStreamWriterWith(&buf, "table", formatter.Options{
+		Fields: "ip,country,isp,is_proxy", Sort: "ip", Width: 50, ASCII: true, Color: true,
+	})
+	if err != nil {
+		t.Fatalf("NewStreamWriterWith failed: %v", err)
+	}
+	sw.Begin()
+	for _, r := range records {
+		sw.Write(r)
+	}
+	if err := sw.End(); err != nil {
+		t.Fatalf("End failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 7 {
+		t.Fatalf("Expected 3 borders, a header and 3 rows, got:\n%s", buf.String())
+	}
+	plain := strings.NewReplacer("\033[31m", "", "\033[0m", "")
+	for _, line := range lines {
+		if n := len([]rune(plain.Replace(line))); n > 50 {
+			t.Errorf("Line is %d columns wide, want at most 50: %q", n, line)
+		}
+	}
+	for i, ip := range []string{"1.1.1.1", "9.9.9.9", "10.0.0.1"} {
+		if !strings.HasPrefix(lines[3+i], "| "+ip+" ") {
+			t.Errorf("Row %d: expected %s first, got %q", i, ip, lines[3+i])
+		}
+	}
+	if !strings.Contains(lines[4], "…") || !strings.Contains(lines[4], "\033[31mYes\033[0m") {
+		t.Errorf("Expected a truncated ISP and a red proxy cell, got %q", lines[4])
+	}
+
+	if _, err := formatter.NewStreamWriterWith(&buf, "csv", formatter.Options{Sort: "ip"}); err == nil {
+		t.Error("Expected streaming formats to refuse -sort")
+	}
+}
+
+func TestTemplateFormatRendersEachRecord(t *testing.T) {
+	records := []*formatter.IPInfo{
+		{IP: "1.1.1.1", Country: "Australia", CountryCode: "au", ISP: "Cloudflare, Inc.", ASN: "AS13335"},
+		{IP: "10.0.0.1", AddressType: "private"},
+	}
+
+	var buf bytes.Buffer
+	sw, err := formatter.NewStreamWriterWith(&buf, "template", formatter.Options{
+		Template: `{{flag .CountryCode}} {{.IP}} {{.Country | default "unknown" | upper}} {{.ISP | truncate 6}} {{printf "%q" .ASN}}`,
+	})
+	if err != nil {
+		t.Fatalf("NewStreamWriterWith failed: %v", err)
+	}
+	for _, r := range records {
+		if err := sw.Write(r); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	want := "🇦🇺 1.1.1.1 AUSTRALIA Cloud… \"AS13335\"\n" +
+		" 10.0.0.1 UNKNOWN  \"\"\n"
+	if buf.String() != want {
+		t.Errorf("Unexpected template output:\n%q\nwant\n%q", buf.String(), want)
+	}
+
+	if _, err := formatter.NewStreamWriterWith(&buf, "template", formatter.Options{}); err == nil {
+		t.Error("Expected the template format to require a template")
+	}
+	for _, bad := range []string{"{{.IP", "{{.Nope}}"} {
+		if _, err := formatter.ParseTemplate(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+	if _, err := formatter.ParseTemplate("{{.Embedded.Country}} {{index .Input 0}}"); err != nil {
+		t.Errorf("Expected a template that depends on the data to parse: %v", err)
+	}
+
+	buf.Reset()
+	sw, err = formatter.NewStreamWriterWith(&buf, "template", formatter.Options{Template: `{{.Sources | default "none"}}`})
+	if err != nil {
+		t.Fatalf("NewStreamWriterWith failed: %v", err)
+	}
+	if err := sw.Write(&formatter.IPInfo{IP: "1.1.1.1", Sources: map[string]string{}}); err != nil || buf.String() != "none\n" {
+		t.Errorf("Expected an empty map to take the default, got %q (%v)", buf.String(), err)
+	}
+
+	if err := formatter.RegisterTemplate("ticket", "{{.IP}} ({{.ASN}})"); err != nil {
+		t.Fatalf("RegisterTemplate failed: %v", err)
+	}
+	out, err := formatter.Format(records[:1], "ticket", "")
+	if err != nil || out != "1.1.1.1 (AS13335)" {
+		t.Errorf("Expected the named template to be a format, got %q (%v)", out, err)
+	}
+	if err := formatter.RegisterTemplate("json", "{{.IP}}"); err == nil {
+		t.Error("Expected a template named after a built-in format to be rejected")
+	}
+}
+
+func TestGeoJSONAndKMLPlaceResults(t *testing.T) {
+	records := []*formatter.IPInfo{
+		{IP: "8.8.8.8", Country: "United States", ASN: "AS15169", Latitude: 37.751, Longitude: -97.822},
+		{IP: "1.1.1.1", Country: "Australia", ASN: "AS13335", ISP: "Cloudflare & Co", Latitude: -33.494, Longitude: 143.2104},
+		{IP: "10.0.0.1", AddressType: "private"},
+	}
+
+	out, err := formatter.Format(records, "geojson", "ip,country")
+	if err != nil {
+		t.Fatalf("GeoJSON failed: %v", err)
+	}
+	var fc struct {
+		Type     string
+		Features []struct {
+			Geometry *struct {
+				Type        string
+				Coordinates []float64
+			}
+			Properties map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal([]byte(out), &fc); err != nil {
+		t.Fatalf("GeoJSON output is not valid JSON: %v\n%s", err, out)
+	}
+	if fc.Type != "FeatureCollection" || len(fc.Features) != 3 {
+		t.Fatalf("Expected a FeatureCollection of 3 features, got %s", out)
+	}
+	if g := fc.Features[0].Geometry; g == nil || g.Type != "Point" || g.Coordinates[0] != -97.822 || g.Coordinates[1] != 37.751 {
+		t.Errorf("Expected a [lon, lat] Point, got %+v", g)
+	}
+	if fc.Features[2].Geometry != nil || fc.Features[2].Properties["ip"] != "10.0.0.1" {
+		t.Errorf("Expected a null geometry for an address without a location, got %+v", fc.Features[2])
+	}
+
+	var buf bytes.Buffer
+	sw, err := formatter.NewStreamWriterWith(&buf, "kml", formatter.Options{GroupBy: "country", Sort: "ip"})
+	if err != nil {
+		t.Fatalf("NewStreamWriterWith failed: %v", err)
+	}
+	sw.Begin()
+	for _, r := range records {
+		sw.Write(r)
+	}
+	if err := sw.End(); err != nil {
+		t.Fatalf("End failed: %v", err)
+	}
+	var doc struct {
+		Folders []struct {
+			Name       string `xml:"name"`
+			Placemarks []struct {
+				Name        string `xml:"name"`
+				Description string `xml:"description"`
+				Coordinates string `xml:"Point>coordinates"`
+			} `xml:"Placemark"`
+		} `xml:"Document>Folder"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("KML output is not valid XML: %v\n%s", err, buf.String())
+	}
+	var names []string
+	for _, f := range doc.Folders {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, ",") != "Australia,United States,Unknown" {
+		t.Fatalf("Expected folders by country with Unknown last, got %v", names)
+	}
+	p := doc.Folders[0].Placemarks[0]
+	if p.Name != "1.1.1.1" || p.Coordinates != "143.2104,-33.494" || !strings.Contains(p.Description, "Cloudflare &amp; Co") {
+		t.Errorf("Unexpected placemark %+v", p)
+	}
+
+	if _, err := formatter.NewStreamWriterWith(&buf, "kml", formatter.Options{GroupBy: "city"}); err == nil {
+		t.Error("Expected an unknown grouping to be rejected")
+	}
+}
@@ -0,0 +1,121 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/config"
+	"github.com/ODIN7h3C0d3r/Netra/internal/core"
+	"github.com/ODIN7h3C0d3r/Netra/internal/iana"
+	"github.com/ODIN7h3C0d3r/Netra/internal/util"
+)
+
+func TestClassifySpecialPurposeAddresses(t *testing.T) {
+	tests := map[string]string{
+		"8.8.8.8":          iana.TypeGlobal,
+		"10.1.2.3":         iana.TypePrivate,
+		"172.31.255.255":   iana.TypePrivate,
+		"172.32.0.1":       iana.TypeGlobal,
+		"100.64.0.1":       iana.TypeCGNAT,
+		"127.8.8.8":        iana.TypeLoopback,
+		"169.254.169.254":  iana.TypeLinkLocal,
+		"192.0.2.10":       iana.TypeDocumentation,
+		"203.0.113.7":      iana.TypeDocumentation,
+		"224.0.0.251":      iana.TypeMulticast,
+		"198.18.0.1":       iana.TypeReserved,
+		"255.255.255.255":  iana.TypeReserved,
+		"0.0.0.0":          iana.TypeReserved,
+		"192.0.0.9":        iana.TypeGlobal,
+		"::1":              iana.TypeLoopback,
+		"::":               iana.TypeReserved,
+		"fe80::1%eth0":     iana.TypeLinkLocal,
+		"fd00::1":          iana.TypePrivate,
+		"ff02::1":          iana.TypeMulticast,
+		"2001:db8::1":      iana.TypeDocumentation,
+		"3fff::1":          iana.TypeDocumentation,
+		"2001:2::1":        iana.TypeReserved,
+		"2001:0:4136::1":   iana.TypeGlobal,
+		"2002:c000:204::1": iana.TypeGlobal,
+		"2606:4700::1111":  iana.TypeGlobal,
+		"::ffff:10.0.0.1":  iana.TypePrivate,
+		"::ffff:8.8.8.8":   iana.TypeGlobal,
+		"4000::1":          iana.TypeReserved,
+	}
+	for ip, want := range tests {
+		block, ok := iana.Lookup(ip)
+		if !ok {
+			t.Errorf("%s: did not parse", ip)
+			continue
+		}
+		if block.Type != want {
+			t.Errorf("%s: got %s (%s), want %s", ip, block.Type, block, want)
+		}
+		if block.Global != (want == iana.TypeGlobal) {
+			t.Errorf("%s: Global = %v", ip, block.Global)
+		}
+	}
+}
+
+func TestNonGlobalAddressesSkipTheProvider(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"country": "Japan"}`))
+	}))
+	defer server.Close()
+
+	cfg := config.Default()
+	cfg.Cache.Enabled = false
+	cfg.Providers.Default = "counting"
+	cfg.Providers.Backends = map[string]config.ProviderConfig{
+		"counting": {Type: "template", URL: server.URL + "/{ip}", Fields: map[string]string{"country": "country"}},
+	}
+	if err := core.Configure(cfg); err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+	defer func() {
+		reset := config.Default()
+		reset.Cache.Enabled = false
+		core.Configure(reset)
+	}()
+
+	info, err := core.GetIPInfo(context.Background(), "192.168.1.1")
+	if err != nil || info.AddressType != iana.TypePrivate || info.Country != "" {
+		t.Errorf("Unexpected result for private address: %+v, %v", info, err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf("Expected no upstream request for a private address, got %d", n)
+	}
+
+	info, err = core.GetIPInfo(context.Background(), "1.1.1.1")
+	if err != nil || info.AddressType != iana.TypeGlobal || info.Country != "Japan" {
+		t.Errorf("Unexpected result for global address: %+v, %v", info, err)
+	}
+}
+
+func TestDecodeTransitionAddresses(t *testing.T) {
+	tests := map[string]util.Transition{
+		"::ffff:8.8.8.8":   {Mechanism: util.TransitionIPv4Mapped, IPv4: "8.8.8.8"},
+		"2002:c000:204::":  {Mechanism: util.Transition6to4, IPv4: "192.0.2.4"},
+		"64:ff9b::808:808": {Mechanism: util.TransitionNAT64, IPv4: "8.8.8.8"},
+		// RFC 4380 example: server 65.54.227.120, client 192.0.2.45:40000
+		"2001:0:4136:e378:8000:63bf:3fff:fdd2": {
+			Mechanism: util.TransitionTeredo, IPv4: "192.0.2.45", Server: "65.54.227.120", Port: 40000,
+		},
+	}
+	for ip, want := range tests {
+		got, ok := util.DecodeTransition(ip)
+		if !ok || got != want {
+			t.Errorf("%s: got %+v, %v; want %+v", ip, got, ok, want)
+		}
+	}
+
+	for _, ip := range []string{"8.8.8.8", "2606:4700::1111", "2001:db8::1", "not an ip"} {
+		if got, ok := util.DecodeTransition(ip); ok {
+			t.Errorf("%s: unexpected transition %+v", ip, got)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/input"
+	"github.com/ODIN7h3C0d3r/Netra/internal/util"
+)
+
+func expandAll(t *testing.T, entry string, opts input.Options) []string {
+	t.Helper()
+	var ips []string
+	if err := input.Expand(entry, opts, func(ip string) bool {
+		ips = append(ips, ip)
+		return true
+	}); err != nil {
+		t.Fatalf("Expand(%q) failed: %v", entry, err)
+	}
+	return ips
+}
+
+func TestExpandCIDRAndRange(t *testing.T) {
+	cases := map[string][]string{
+		"8.8.8.8":                      {"8.8.8.8"},
+		"192.0.2.5/30":                 {"192.0.2.4", "192.0.2.5", "192.0.2.6", "192.0.2.7"},
+		"192.0.2.254-192.0.3.1":        {"192.0.2.254", "192.0.2.255", "192.0.3.0", "192.0.3.1"},
+		"2001:db8::fffe-2001:db8::1:0": {"2001:db8::fffe", "2001:db8::ffff", "2001:db8::1:0"},
+	}
+	for entry, want := range cases {
+		if got := expandAll(t, entry, input.Options{}); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expand(%q) = %v, want %v", entry, got, want)
+		}
+	}
+}
+
+func TestExpandSamplesOnePerBlock(t *testing.T) {
+	got := expandAll(t, "10.0.0.0/22", input.Options{Sample: true})
+	want := []string{"10.0.0.1", "10.0.1.1", "10.0.2.1", "10.0.3.1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Sampled /22 = %v, want %v", got, want)
+	}
+
+	// A /8 is 65536 blocks, which fits the default limit when sampling
+	n := 0
+	input.Expand("10.0.0.0/8", input.Options{Sample: true, MaxExpand: input.DefaultMaxExpand}, func(string) bool {
+		n++
+		return n < 3
+	})
+	if n != 3 {
+		t.Errorf("Expected expansion to stop when the callback returns false, got %d", n)
+	}
+}
+
+func TestExpandRejectsOversizedAndInvalid(t *testing.T) {
+	err := input.Expand("10.0.0.0/8", input.Options{MaxExpand: input.DefaultMaxExpand}, func(string) bool {
+		t.Fatal("Oversized entry must not yield addresses")
+		return false
+	})
+	var tooLarge *input.TooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Size.String() != "16777216" {
+		t.Errorf("Expected TooLargeError for a /8, got %v", err)
+	}
+
+	for _, bad := range []string{"999.1.1.1", "10.0.0.9-10.0.0.1", "10.0.0.1-::1", "10.0.0.0/33"} {
+		if err := input.Expand(bad, input.Options{}, func(string) bool { return true }); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestHostnameFromEntry(t *testing.T) {
+	cases := map[string]string{
+		"example.com":                      "example.com",
+		"Example.COM.":                     "example.com",
+		"example.com:8443":                 "example.com",
+		"https://www.example.org/path?q=1": "www.example.org",
+		"http://198.51.100.7:8080/":        "198.51.100.7",
+		"alice@mail.example.net":           "mail.example.net",
+		"8.8.8.8":                          "",
+		"2001:db8::1":                      "",
+		"10.0.0.0/24":                      "",
+		"10.0.0.1-10.0.0.5":                "",
+		"999.1.1.1":                        "",
+		"not a host":                       "",
+	}
+	for entry, want := range cases {
+		got, ok := input.Hostname(entry)
+		if ok != (want != "") || got != want {
+			t.Errorf("Hostname(%q) = %q, %v; want %q", entry, got, ok, want)
+		}
+	}
+}
+
+func TestReadEntriesSkipsComments(t *testing.T) {
+	in := "# scanners seen today\n8.8.8.8\n\n  1.1.1.1   # cloudflare\n10.0.0.0/30\t# lab\nhttps://example.com/#top\n"
+	var got []string
+	if err := input.ReadEntries(strings.NewReader(in), func(entry string) bool {
+		got = append(got, entry)
+		return true
+	}); err != nil {
+		t.Fatalf("ReadEntries failed: %v", err)
+	}
+
+	want := []string{"8.8.8.8", "1.1.1.1", "10.0.0.0/30", "https://example.com/#top"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadEntries = %v, want %v", got, want)
+	}
+}
+
+func TestExtractIPsFromText(t *testing.T) {
+	cases := map[string][]string{
+		"Blocked 203.0.113.9:443 and (198.51.100.7).":                {"203.0.113.9", "198.51.100.7"},
+		"C2 at hxxp://8.8.8[.]8/gate.php and 1.1.1(.)1":              {"8.8.8.8", "1.1.1.1"},
+		"beacon to 192[dot]0[dot]2[dot]44 over [2001:db8::1]:8443":   {"192.0.2.44", "2001:db8::1"},
+		"Source:2001:DB8:0:0::10, link fe80::1%eth0.":                {"2001:db8::10", "fe80::1"},
+		"mapped ::ffff:192.0.2.1 here":                               {"::ffff:192.0.2.1"},
+		"version 1.2.3.4.5, OID 1.3.6.1.4.1, 999.1.1.1, at 12:30:45": nil,
+		"std::vector and 00:1a:2b:3c:4d:5e":                          nil,
+	}
+	for line, want := range cases {
+		if got := input.ExtractIPs(line); !reflect.DeepEqual(got, want) {
+			t.Errorf("ExtractIPs(%q) = %v, want %v", line, got, want)
+		}
+	}
+}
+
+func TestExtractReportsFirstLine(t *testing.T) {
+	text := "first 8.8.8.8\nnothing here\nagain 8.8.8.8 and 1.1.1.1\n"
+	var got []string
+	if err := input.Extract(strings.NewReader(text), true, func(ip string, line int) bool {
+		got = append(got, fmt.Sprintf("%s@%d", ip, line))
+		return true
+	}); err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+
+	want := []string{"8.8.8.8@1", "1.1.1.1@3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract = %v, want %v", got, want)
+	}
+}
+
+func TestStructuredInputKeepsColumns(t *testing.T) {
+	var csvEntries []input.Entry
+	csvIn := "timestamp,user,src_ip\n2026-10-18T10:00:00Z,alice,192.0.2.7\n"
+	if err := input.ReadCSV(strings.NewReader(csvIn), "", func(e input.Entry) bool {
+		csvEntries = append(csvEntries, e)
+		return true
+	}); err != nil {
+		t.Fatalf("ReadCSV failed: %v", err)
+	}
+	if len(csvEntries) != 1 || csvEntries[0].Text != "192.0.2.7" || len(csvEntries[0].Columns) != 3 || csvEntries[0].Columns[1].Value != "alice" {
+		t.Errorf("ReadCSV = %+v", csvEntries)
+	}
+	if err := input.ReadCSV(strings.NewReader("a,b\n1,2\n"), "", func(input.Entry) bool { return true }); err == nil {
+		t.Error("ReadCSV accepted a header without an IP column")
+	}
+
+	var jsonEntries []input.Entry
+	jsonIn := `{"user":"bob","source":{"ip":"2001:db8::9"},"bytes":1700000000000}` + "\n"
+	if err := input.ReadJSONL(strings.NewReader(jsonIn), "$.source.ip", func(e input.Entry) bool {
+		jsonEntries = append(jsonEntries, e)
+		return true
+	}); err != nil {
+		t.Fatalf("ReadJSONL failed: %v", err)
+	}
+	if len(jsonEntries) != 1 || jsonEntries[0].Text != "2001:db8::9" {
+		t.Fatalf("ReadJSONL = %+v", jsonEntries)
+	}
+	var names []string
+	for _, c := range jsonEntries[0].Columns {
+		names = append(names, c.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"user", "source", "bytes"}) {
+		t.Errorf("columns out of document order: %v", names)
+	}
+}
+
+func TestCanonicalAddresses(t *testing.T) {
+	tests := []struct {
+		in    string
+		unmap bool
+		want  string
+	}{
+		{"8.8.8.8", false, "8.8.8.8"},
+		{"008.008.008.008", false, "8.8.8.8"},
+		{"010.0.0.1", false, "10.0.0.1"},
+		{"2001:DB8::1", false, "2001:db8::1"},
+		{"2001:db8:0::1", false, "2001:db8::1"},
+		{"2001:0db8:0000:0000:0000:0000:0000:0001", false, "2001:db8::1"},
+		{"[2001:db8::1]", false, "2001:db8::1"},
+		{"fe80::1%eth0", false, "fe80::1"},
+		{"::FFFF:008.008.008.008", false, "::ffff:8.8.8.8"},
+		{"::ffff:8.8.8.8", true, "8.8.8.8"},
+	}
+	for _, tt := range tests {
+		got, err := util.CanonicalIP(tt.in, tt.unmap)
+		if err != nil || got != tt.want {
+			t.Errorf("CanonicalIP(%q, %v) = %q, %v; want %q", tt.in, tt.unmap, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"256.1.1.1", "1.2.3", "0x8.8.8.8", "8.8.8.8%eth0", "2001:db8::g"} {
+		if got, err := util.CanonicalIP(bad, false); err == nil {
+			t.Errorf("CanonicalIP(%q) = %q, want an error", bad, got)
+		}
+	}
+
+	if got := expandAll(t, "010.000.000.000/30", input.Options{}); !reflect.DeepEqual(got, []string{"10.0.0.0", "10.0.0.1", "10.0.0.2", "10.0.0.3"}) {
+		t.Errorf("Expected leading zeros to be stripped in CIDRs, got %v", got)
+	}
+	if got := expandAll(t, "2001:DB8:0::1", input.Options{}); !reflect.DeepEqual(got, []string{"2001:db8::1"}) {
+		t.Errorf("Expected the canonical form, got %v", got)
+	}
+}
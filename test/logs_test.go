@@ -0,0 +1,67 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/logs"
+	"github.com/ODIN7h3C0d3r/Netra/internal/util"
+)
+
+func TestLogParsers(t *testing.T) {
+	now := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		format, path, line string
+		want               logs.Record
+	}{
+		{"nginx-combined", "", `203.0.113.9 - bob [18/Oct/2026:10:22:01 +0000] "GET /login?next=/ HTTP/1.1" 200 512 "-" "curl/8.0"`,
+			logs.Record{IP: "203.0.113.9", Time: time.Date(2026, 10, 18, 10, 22, 1, 0, time.UTC), Path: "/login", User: "bob"}},
+		{"apache", "", `2001:db8::7 - - [18/Oct/2026:10:22:01 +0000] "GET / HTTP/1.0" 200 12`,
+			logs.Record{IP: "2001:db8::7", Time: time.Date(2026, 10, 18, 10, 22, 1, 0, time.UTC), Path: "/"}},
+		// Syslog has no year; December lines read in January belong to last year
+		{"sshd", "", `Dec 31 23:59:01 web1 sshd[812]: Failed password for invalid user admin from 198.51.100.4 port 52311 ssh2`,
+			logs.Record{IP: "198.51.100.4", Time: time.Date(2025, 12, 31, 23, 59, 1, 0, time.UTC), User: "admin"}},
+		{"jsonl", "$.request.remote_ip", `{"ts":"2026-10-18T10:22:01Z","request":{"remote_ip":"192.0.2.8:5555","uri":"/api?x=1"}}`,
+			logs.Record{IP: "192.0.2.8", Time: time.Date(2026, 10, 18, 10, 22, 1, 0, time.UTC), Path: "/api"}},
+	}
+
+	for _, tc := range cases {
+		p, err := logs.NewParser(tc.format, logs.Options{JSONPath: tc.path, Now: now})
+		if err != nil {
+			t.Fatalf("NewParser(%q) failed: %v", tc.format, err)
+		}
+		got, ok := p.Parse(tc.line)
+		if !ok {
+			t.Errorf("%s: line not parsed: %s", tc.format, tc.line)
+			continue
+		}
+		if got.IP != tc.want.IP || !got.Time.Equal(tc.want.Time) || got.Path != tc.want.Path || got.User != tc.want.User {
+			t.Errorf("%s: got %+v, want %+v", tc.format, got, tc.want)
+		}
+	}
+
+	sshd, _ := logs.NewParser("sshd", logs.Options{Now: now})
+	if _, ok := sshd.Parse("Oct 18 10:24:02 web1 CRON[1]: session opened for user root"); ok {
+		t.Error("sshd parser accepted a non-sshd line")
+	}
+}
+
+func TestAggregatorRanksClients(t *testing.T) {
+	agg := logs.NewAggregator()
+	for _, r := range []logs.Record{
+		{IP: "192.0.2.1", Path: "/a"},
+		{IP: "192.0.2.2", Path: "/login"},
+		{IP: "192.0.2.2", Path: "/login"},
+		{IP: "192.0.2.2", Path: "/admin"},
+	} {
+		agg.Add(r)
+	}
+
+	results := agg.Results()
+	if len(results) != 2 || results[0].IP != "192.0.2.2" || results[0].Requests != 3 {
+		t.Fatalf("unexpected ranking: %+v", results)
+	}
+	if got := util.TopCounts(results[0].Paths, 1); got != "/login (2)" {
+		t.Errorf("TopCounts = %q, want %q", got, "/login (2)")
+	}
+}
@@ -0,0 +1,234 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/mmdb"
+	"github.com/ODIN7h3C0d3r/Netra/internal/provider"
+)
+
+// mmdbEncode writes a value in MaxMind DB data section encoding
+func mmdbEncode(b *bytes.Buffer, v interface{}) {
+	ctrl := func(typ, size int) {
+		sizeBits, extra := size, []byte(nil)
+		if size >= 29 {
+			sizeBits, extra = 29, []byte{byte(size - 29)}
+		}
+		if typ > 7 {
+			b.WriteByte(byte(sizeBits))
+			b.WriteByte(byte(typ - 7))
+		} else {
+			b.WriteByte(byte(typ<<5 | sizeBits))
+		}
+		b.Write(extra)
+	}
+
+	switch t := v.(type) {
+	case string:
+		ctrl(2, len(t))
+		b.WriteString(t)
+	case float64:
+		ctrl(3, 8)
+		binary.Write(b, binary.BigEndian, math.Float64bits(t))
+	case uint32:
+		ctrl(6, 4)
+		binary.Write(b, binary.BigEndian, t)
+	case bool:
+		size := 0
+		if t {
+			size = 1
+		}
+		ctrl(14, size)
+	case []interface{}:
+		ctrl(11, len(t))
+		for _, e := range t {
+			mmdbEncode(b, e)
+		}
+	case map[string]interface{}:
+		ctrl(7, len(t))
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			mmdbEncode(b, k)
+			mmdbEncode(b, t[k])
+		}
+	}
+}
+
+// buildMMDB creates an IPv6 database with 24-bit records holding a single record for ipnet
+func buildMMDB(t *testing.T, dbType string, ipnet string, record map[string]interface{}) string {
+	return buildMMDBVersion(t, 6, dbType, ipnet, record)
+}
+
+// buildMMDBVersion is buildMMDB for an IPv4-only (4) or IPv6 (6) database
+func buildMMDBVersion(t *testing.T, ipVersion uint32, dbType string, ipnet string, record map[string]interface{}) string {
+	_, network, err := net.ParseCIDR(ipnet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ones, _ := network.Mask.Size()
+	addr := network.IP.To16()
+	if v4 := network.IP.To4(); v4 != nil && ipVersion == 4 {
+		addr = v4
+	} else if v4 != nil {
+		// IPv4 networks live under ::/96, not the ::ffff:0:0/96 mapped range
+		addr = append(make(net.IP, 12), v4...)
+		ones += 96
+	}
+
+	nodeCount := ones
+	var tree bytes.Buffer
+	for i := 0; i < ones; i++ {
+		bit := int(addr[i/8]>>(7-uint(i%8))) & 1
+		next := i + 1
+		if next == ones {
+			next = nodeCount + 16 // first byte of the data section
+		}
+		records := [2]int{nodeCount, nodeCount}
+		records[bit] = next
+		for _, r := range records {
+			tree.Write([]byte{byte(r >> 16), byte(r >> 8), byte(r)})
+		}
+	}
+
+	var file bytes.Buffer
+	file.Write(tree.Bytes())
+	file.Write(make([]byte, 16))
+	mmdbEncode(&file, record)
+	file.WriteString("\xAB\xCD\xEFMaxMind.com")
+	mmdbEncode(&file, map[string]interface{}{
+		"node_count":    uint32(nodeCount),
+		"record_size":   uint32(24),
+		"ip_version":    ipVersion,
+		"database_type": dbType,
+		"languages":     []interface{}{"en"},
+	})
+
+	path := filepath.Join(t.TempDir(), dbType+".mmdb")
+	if err := os.WriteFile(path, file.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMMDBReaderLookup(t *testing.T) {
+	path := buildMMDB(t, "GeoLite2-City", "81.2.69.0/24", map[string]interface{}{
+		"country":  map[string]interface{}{"iso_code": "GB", "names": map[string]interface{}{"en": "United Kingdom"}},
+		"location": map[string]interface{}{"latitude": 51.5142, "longitude": -0.0931, "time_zone": "Europe/London"},
+	})
+
+	r, err := mmdb.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if r.Metadata.DatabaseType != "GeoLite2-City" || r.Metadata.RecordSize != 24 {
+		t.Errorf("Unexpected metadata: %+v", r.Metadata)
+	}
+
+	rec, err := r.Lookup(net.ParseIP("81.2.69.160"))
+	if err != nil || rec == nil {
+		t.Fatalf("Expected record for 81.2.69.160, got %v (err %v)", rec, err)
+	}
+	if mmdb.Path(rec, "country", "iso_code") != "GB" {
+		t.Errorf("Unexpected record: %v", rec)
+	}
+
+	rec, err = r.Lookup(net.ParseIP("81.2.70.1"))
+	if err != nil || rec != nil {
+		t.Errorf("Expected no record outside the network, got %v (err %v)", rec, err)
+	}
+
+	v4, err := mmdb.Open(buildMMDBVersion(t, 4, "GeoLite2-Country", "81.2.69.0/24", map[string]interface{}{
+		"country": map[string]interface{}{"iso_code": "GB"},
+	}))
+	if err != nil {
+		t.Fatalf("Failed to open IPv4 database: %v", err)
+	}
+	if rec, err := v4.Lookup(net.ParseIP("81.2.69.160")); err != nil || rec == nil {
+		t.Errorf("Expected record in the IPv4 database, got %v (err %v)", rec, err)
+	}
+	if rec, err := v4.Lookup(net.ParseIP("2001:db8::1")); err != nil || rec != nil {
+		t.Errorf("Expected an IPv6 address to be a miss in an IPv4 database, got %v (err %v)", rec, err)
+	}
+}
+
+func TestMMDBProviderMergesCityAndASN(t *testing.T) {
+	city := buildMMDB(t, "GeoLite2-City", "1.0.0.0/24", map[string]interface{}{
+		"city":         map[string]interface{}{"names": map[string]interface{}{"en": "Brisbane"}},
+		"continent":    map[string]interface{}{"code": "OC"},
+		"country":      map[string]interface{}{"iso_code": "AU"},
+		"subdivisions": []interface{}{map[string]interface{}{"names": map[string]interface{}{"en": "Queensland"}}},
+		"postal":       map[string]interface{}{"code": "4000"},
+		"traits":       map[string]interface{}{"is_anonymous_proxy": true},
+	})
+	asn := buildMMDB(t, "GeoLite2-ASN", "1.0.0.0/24", map[string]interface{}{
+		"autonomous_system_number":       uint32(13335),
+		"autonomous_system_organization": "CLOUDFLARENET",
+	})
+
+	p, err := provider.New("mmdb", provider.Settings{Databases: []string{city, asn}}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	if !p.Capabilities().Local {
+		t.Error("mmdb provider should report itself as local")
+	}
+
+	info, err := p.Lookup(context.Background(), "1.0.0.1")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if info.City != "Brisbane" || info.Region != "Queensland" || info.Country != "Australia" ||
+		info.Continent != "Oceania" || info.Postal != "4000" || !info.IsProxy {
+		t.Errorf("Unexpected geo fields: %+v", info)
+	}
+	if info.ASN != "AS13335" || info.Org != "CLOUDFLARENET" {
+		t.Errorf("Unexpected ASN fields: %q %q", info.ASN, info.Org)
+	}
+
+	if _, err := p.Lookup(context.Background(), "9.9.9.9"); err == nil {
+		t.Error("Expected not-found error for address outside the databases")
+	}
+}
+
+func TestMMDBCapabilitiesFollowTheDatabases(t *testing.T) {
+	city := buildMMDB(t, "GeoLite2-City", "1.0.0.0/24", map[string]interface{}{
+		"country": map[string]interface{}{"iso_code": "AU"},
+	})
+	p, err := provider.New("mmdb", provider.Settings{Databases: []string{city}}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	if caps := p.Capabilities(); caps.Proxy || caps.Hosting || caps.ASN {
+		t.Errorf("A GeoLite2 City database has no proxy, hosting or ASN data, got %+v", caps)
+	}
+
+	anon := buildMMDB(t, "GeoIP2-Anonymous-IP", "1.0.0.0/28", map[string]interface{}{
+		"is_anonymous": true, "is_hosting_provider": true,
+	})
+	p, err = provider.New("mmdb", provider.Settings{Databases: []string{city, anon}}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	if caps := p.Capabilities(); !caps.Proxy || !caps.Hosting {
+		t.Errorf("Expected the Anonymous IP database to add proxy and hosting, got %+v", caps)
+	}
+	info, err := p.Lookup(context.Background(), "1.0.0.1")
+	if err != nil || !info.IsProxy || !info.IsHosting || info.CountryCode != "AU" {
+		t.Errorf("Expected a flagged Australian address, got %+v (%v)", info, err)
+	}
+	if info, err = p.Lookup(context.Background(), "1.0.0.200"); err != nil || info.IsProxy || info.IsHosting {
+		t.Errorf("Expected an unflagged address outside the anonymous networks, got %+v (%v)", info, err)
+	}
+}
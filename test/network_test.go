@@ -1 +1,294 @@
 package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/config"
+	"github.com/ODIN7h3C0d3r/Netra/internal/core"
+	"github.com/ODIN7h3C0d3r/Netra/internal/formatter"
+	"github.com/ODIN7h3C0d3r/Netra/internal/network"
+	"github.com/ODIN7h3C0d3r/Netra/internal/provider"
+)
+
+func newTestClient(t *testing.T) *network.CustomHTTPClient {
+	client, err := network.NewCustomHTTPClient(network.HTTPClientConfig{RetryLimit: 1})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return client
+}
+
+func TestIPInfoProviderNormalizes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/8.8.8.8/json" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"ip":"8.8.8.8","city":"Mountain View","region":"California","country":"US","loc":"37.4056,-122.0775","org":"AS15169 Google LLC","timezone":"America/Los_Angeles"}`))
+	}))
+	defer server.Close()
+
+	p, err := provider.New("ipinfo", provider.Settings{BaseURL: server.URL}, newTestClient(t))
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	info, err := p.Lookup(context.Background(), "8.8.8.8")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if info.Country != "United States" || info.CountryCode != "US" {
+		t.Errorf("Expected country to be expanded from code, got %q/%q", info.Country, info.CountryCode)
+	}
+	if info.ASN != "AS15169" || info.Org != "Google LLC" {
+		t.Errorf("Expected ASN and org split from org field, got %q/%q", info.ASN, info.Org)
+	}
+	if info.Latitude != 37.4056 || info.Longitude != -122.0775 {
+		t.Errorf("Expected coordinates from loc, got %v,%v", info.Latitude, info.Longitude)
+	}
+	if info.Provider != "ipinfo" {
+		t.Errorf("Expected provider name to be recorded, got %q", info.Provider)
+	}
+}
+
+func TestTemplateProviderMapsFields(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("key") != "secret" {
+			t.Errorf("Expected token in query, got %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`{"data":{"geo":{"cc":"DE","lat":"52.52","lon":13.4}},"net":[{"asn":"AS3320"}],"vpn":true}`))
+	}))
+	defer server.Close()
+
+	p, err := provider.New("mygeo", provider.Settings{
+		Type:  "template",
+		URL:   server.URL + "/lookup/{ip}?key={token}",
+		Token: "secret",
+		Fields: map[string]string{
+			"country_code": "data.geo.cc",
+			"latitude":     "data.geo.lat",
+			"longitude":    "data.geo.lon",
+			"asn":          "net.0.asn",
+			"is_proxy":     "vpn",
+		},
+	}, newTestClient(t))
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	info, err := p.Lookup(context.Background(), "1.2.3.4")
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if info.Country != "Germany" || info.ASN != "AS3320" || !info.IsProxy || info.Latitude != 52.52 {
+		t.Errorf("Template mapping produced unexpected result: %+v", info)
+	}
+	if !p.Capabilities().Proxy || p.Capabilities().Mobile {
+		t.Errorf("Capabilities should follow the field mapping: %+v", p.Capabilities())
+	}
+}
+
+func TestTemplateProviderRejectsUnknownField(t *testing.T) {
+	_, err := provider.New("bad", provider.Settings{
+		Type:   "template",
+		URL:    "https://example.com/{ip}",
+		Fields: map[string]string{"colour": "x"},
+	}, newTestClient(t))
+	if err == nil {
+		t.Error("Expected error for unknown field mapping")
+	}
+}
+
+// staticProvider returns a fixed answer or error
+type staticProvider struct {
+	name string
+	info *formatter.IPInfo
+	err  error
+	caps provider.Capabilities
+}
+
+func (p *staticProvider) Name() string                        { return p.name }
+func (p *staticProvider) Capabilities() provider.Capabilities { return p.caps }
+func (p *staticProvider) Lookup(ctx context.Context, ip string) (*formatter.IPInfo, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	info := *p.info
+	return &info, nil
+}
+
+func TestChainFallsBackOnError(t *testing.T) {
+	chain := provider.NewChain(
+		&staticProvider{name: "first", err: &network.RateLimitError{}},
+		&staticProvider{name: "second", info: &formatter.IPInfo{IP: "1.1.1.1", Country: "Australia", Provider: "second"}},
+	)
+
+	info, err := chain.Lookup(context.Background(), "1.1.1.1")
+	if err != nil {
+		t.Fatalf("Chain lookup failed: %v", err)
+	}
+	if info.Provider != "second" {
+		t.Errorf("Expected answer from second provider, got %q", info.Provider)
+	}
+}
+
+func TestConsensusMajorityVote(t *testing.T) {
+	asn := provider.Capabilities{ASN: true}
+	consensus := provider.NewConsensus(
+		&staticProvider{name: "a", caps: provider.Capabilities{ASN: true, Hosting: true}, info: &formatter.IPInfo{Country: "France", City: "Paris", ASN: "AS1", IsHosting: true, Latitude: 48.85, Longitude: 2.35}},
+		&staticProvider{name: "b", caps: asn, info: &formatter.IPInfo{Country: "france", City: "Lyon", ASN: "AS1", Latitude: 45.76, Longitude: 4.83}},
+		&staticProvider{name: "c", info: &formatter.IPInfo{Country: "Germany", City: "Lyon", ASN: "AS9", Latitude: 45.77, Longitude: 4.84}},
+		&staticProvider{name: "d", err: &network.RateLimitError{}},
+	)
+
+	info, err := consensus.Lookup(context.Background(), "192.0.2.1")
+	if err != nil {
+		t.Fatalf("Consensus lookup failed: %v", err)
+	}
+	if info.Country != "France" || info.Sources["country"] != "a+b" {
+		t.Errorf("Expected France from a+b, got %q from %q", info.Country, info.Sources["country"])
+	}
+	if info.City != "Lyon" || info.Sources["city"] != "b+c" {
+		t.Errorf("Expected Lyon from b+c, got %q from %q", info.City, info.Sources["city"])
+	}
+	if info.Latitude != 45.76 || info.Longitude != 4.83 {
+		t.Errorf("Expected location from the first Lyon answer, got %v,%v", info.Latitude, info.Longitude)
+	}
+	if info.ASN != "AS1" || info.Disagreements["asn"] != nil {
+		t.Errorf("Provider without ASN support should not vote on asn: %q %v", info.ASN, info.Disagreements["asn"])
+	}
+	if !info.IsHosting {
+		t.Error("Providers that cannot detect hosting should not outvote one that can")
+	}
+	if info.Disagreements["city"]["a"] != "Paris" {
+		t.Errorf("Expected disagreement on city to be recorded, got %v", info.Disagreements["city"])
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	cases := map[string]network.Rate{
+		"45/min":    {Count: 45, Per: time.Minute},
+		"1000/day":  {Count: 1000, Per: 24 * time.Hour},
+		"10/s":      {Count: 10, Per: time.Second},
+		"5/hours":   {Count: 5, Per: time.Hour},
+		"unlimited": {},
+	}
+	for in, want := range cases {
+		got, err := network.ParseRate(in)
+		if err != nil || got != want {
+			t.Errorf("ParseRate(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"45", "x/min", "-1/min", "10/fortnight"} {
+		if _, err := network.ParseRate(bad); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestDNSServersGetDefaultPort(t *testing.T) {
+	cases := map[string]string{
+		"8.8.8.8":       "8.8.8.8:53",
+		"[::1]":         "[::1]:53",
+		"2001:4860::88": "[2001:4860::88]:53",
+		"1.1.1.1:5353":  "1.1.1.1:5353",
+	}
+	for in, want := range cases {
+		cfg := config.Default()
+		cfg.Network.DNSServers = []string{in}
+		if err := cfg.Validate(); err != nil || cfg.Network.DNSServers[0] != want {
+			t.Errorf("%s: got %v, %v; want %s", in, cfg.Network.DNSServers, err, want)
+		}
+	}
+}
+
+func TestRateLimiterBucket(t *testing.T) {
+	limiter := network.NewRateLimiter(network.Rate{Count: 2, Per: time.Hour})
+	for i := 0; i < 2; i++ {
+		if _, ok := limiter.Allow(); !ok {
+			t.Fatalf("Expected token %d to be available", i+1)
+		}
+	}
+	d, ok := limiter.Allow()
+	if ok || d < 29*time.Minute {
+		t.Errorf("Expected empty bucket with ~30m wait, got ok=%v wait=%v", ok, d)
+	}
+
+	// Retries go out on an empty bucket, but not while a 429 pauses it
+	if _, ok := limiter.AllowRetry(); !ok {
+		t.Error("Expected a retry not to wait for the next token")
+	}
+	limiter.Pause(time.Minute)
+	if d, ok := limiter.AllowRetry(); ok || d < 59*time.Second {
+		t.Errorf("Expected a retry to wait out the pause, got ok=%v wait=%v", ok, d)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); err == nil {
+		t.Error("Expected Wait to give up when the context ends")
+	}
+}
+
+func TestRateLimitedProviderPausesOnRetryAfter(t *testing.T) {
+	limiter := network.NewRateLimiter(network.Rate{Count: 100, Per: time.Second})
+	limited := provider.NewRateLimited(&staticProvider{name: "busy", err: &network.RateLimitError{RetryAfter: time.Minute}}, limiter, false)
+
+	if _, err := limited.Lookup(context.Background(), "1.1.1.1"); err == nil {
+		t.Fatal("Expected the 429 to be returned")
+	}
+
+	// Retry-After must hold back every later lookup sharing the limiter
+	_, err := limited.Lookup(context.Background(), "8.8.8.8")
+	rateErr, ok := err.(*network.RateLimitError)
+	if !ok || rateErr.RetryAfter < 59*time.Second {
+		t.Errorf("Expected lookup to be held back by Retry-After, got %v", err)
+	}
+}
+
+func TestConcurrentLookupsShareOneRequest(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(`{"country": "Japan"}`))
+	}))
+	defer server.Close()
+
+	cfg := config.Default()
+	cfg.Cache.Enabled = false
+	cfg.Providers.Default = "dedupe"
+	cfg.Providers.Backends = map[string]config.ProviderConfig{
+		"dedupe": {Type: "template", URL: server.URL + "/{ip}", Fields: map[string]string{"country": "country"}},
+	}
+	if err := core.Configure(cfg); err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+	defer func() {
+		reset := config.Default()
+		reset.Cache.Enabled = false
+		core.Configure(reset)
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			info, err := core.GetIPInfo(context.Background(), "198.41.0.4")
+			if err != nil || info.Country != "Japan" {
+				t.Errorf("Unexpected result: %+v, %v", info, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("Expected 1 upstream request for 20 concurrent lookups, got %d", n)
+	}
+}
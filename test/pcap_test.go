@@ -0,0 +1,161 @@
+package test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/netip"
+	"testing"
+
+	"github.com/ODIN7h3C0d3r/Netra/internal/pcap"
+)
+
+// ethernetIPv4TCP builds an Ethernet frame carrying an IPv4 TCP segment
+func ethernetIPv4TCP(src, dst [4]byte, sport, dport uint16) []byte {
+	frame := make([]byte, 14+20+20)
+	binary.BigEndian.PutUint16(frame[12:14], 0x0800)
+	ip := frame[14:]
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:4], 40)
+	ip[9] = 6
+	copy(ip[12:16], src[:])
+	copy(ip[16:20], dst[:])
+	binary.BigEndian.PutUint16(ip[20:22], sport)
+	binary.BigEndian.PutUint16(ip[22:24], dport)
+	return frame
+}
+
+// vlanIPv6UDP builds an 802.1Q-tagged Ethernet frame carrying an IPv6 UDP datagram
+func vlanIPv6UDP(src, dst netip.Addr, sport, dport uint16) []byte {
+	frame := make([]byte, 18+40+8)
+	binary.BigEndian.PutUint16(frame[12:14], 0x8100)
+	binary.BigEndian.PutUint16(frame[16:18], 0x86dd)
+	ip := frame[18:]
+	ip[0] = 0x60
+	ip[6] = 17
+	s, d := src.As16(), dst.As16()
+	copy(ip[8:24], s[:])
+	copy(ip[24:40], d[:])
+	binary.BigEndian.PutUint16(ip[40:42], sport)
+	binary.BigEndian.PutUint16(ip[42:44], dport)
+	return frame
+}
+
+func classicPcap(frames ...[]byte) []byte {
+	var buf bytes.Buffer
+	hdr := make([]byte, 24)
+	binary.LittleEndian.PutUint32(hdr[0:4], 0xa1b2c3d4)
+	binary.LittleEndian.PutUint16(hdr[4:6], 2)
+	binary.LittleEndian.PutUint16(hdr[6:8], 4)
+	binary.LittleEndian.PutUint32(hdr[16:20], 65535)
+	binary.LittleEndian.PutUint32(hdr[20:24], pcap.LinkTypeEthernet)
+	buf.Write(hdr)
+	for i, f := range frames {
+		rec := make([]byte, 16)
+		binary.LittleEndian.PutUint32(rec[0:4], uint32(1760000000+i))
+		binary.LittleEndian.PutUint32(rec[8:12], uint32(len(f)))
+		binary.LittleEndian.PutUint32(rec[12:16], uint32(len(f)))
+		buf.Write(rec)
+		buf.Write(f)
+	}
+	return buf.Bytes()
+}
+
+func pcapngBlock(typ uint32, body []byte) []byte {
+	for len(body)%4 != 0 {
+		body = append(body, 0)
+	}
+	total := uint32(12 + len(body))
+	b := make([]byte, 8, total)
+	binary.BigEndian.PutUint32(b[0:4], typ)
+	binary.BigEndian.PutUint32(b[4:8], total)
+	b = append(b, body...)
+	return binary.BigEndian.AppendUint32(b, total)
+}
+
+func pcapng(frames ...[]byte) []byte {
+	var buf bytes.Buffer
+	shb := make([]byte, 16)
+	binary.BigEndian.PutUint32(shb[0:4], 0x1a2b3c4d)
+	binary.BigEndian.PutUint16(shb[4:6], 1)
+	binary.BigEndian.PutUint64(shb[8:16], ^uint64(0))
+	buf.Write(pcapngBlock(0x0a0d0d0a, shb))
+
+	// Ethernet interface with nanosecond timestamps (if_tsresol = 9)
+	idb := make([]byte, 8, 20)
+	binary.BigEndian.PutUint16(idb[0:2], pcap.LinkTypeEthernet)
+	idb = append(idb, 0, 9, 0, 1, 9, 0, 0, 0, 0, 0, 0, 0)
+	buf.Write(pcapngBlock(1, idb))
+
+	for _, f := range frames {
+		epb := make([]byte, 20)
+		ts := uint64(1760000000) * 1e9
+		binary.BigEndian.PutUint32(epb[4:8], uint32(ts>>32))
+		binary.BigEndian.PutUint32(epb[8:12], uint32(ts))
+		binary.BigEndian.PutUint32(epb[12:16], uint32(len(f)))
+		binary.BigEndian.PutUint32(epb[16:20], uint32(len(f)))
+		buf.Write(pcapngBlock(6, append(epb, f...)))
+	}
+	return buf.Bytes()
+}
+
+func collect(t *testing.T, capture []byte) map[string]*pcap.Endpoint {
+	t.Helper()
+	rd, err := pcap.NewReader(bytes.NewReader(capture))
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	endpoints := pcap.NewEndpoints(nil)
+	for {
+		p, err := rd.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		if flow, ok := pcap.Decode(p.LinkType, p.Data); ok {
+			endpoints.Add(p, flow)
+		}
+	}
+
+	byIP := make(map[string]*pcap.Endpoint)
+	for _, ep := range endpoints.Results() {
+		byIP[ep.Addr.String()] = ep
+	}
+	return byIP
+}
+
+func TestPcapFormatsCountEndpoints(t *testing.T) {
+	client, server := [4]byte{192, 0, 2, 10}, [4]byte{198, 51, 100, 80}
+	v6a, v6b := netip.MustParseAddr("2001:db8::1"), netip.MustParseAddr("2001:db8::53")
+	frames := [][]byte{
+		ethernetIPv4TCP(client, server, 51000, 443),
+		ethernetIPv4TCP(server, client, 443, 51000),
+		vlanIPv6UDP(v6a, v6b, 40000, 53),
+		make([]byte, 60), // not IP
+	}
+
+	for name, capture := range map[string][]byte{"pcap": classicPcap(frames...), "pcapng": pcapng(frames...)} {
+		eps := collect(t, capture)
+		if len(eps) != 4 {
+			t.Fatalf("%s: got %d endpoints, want 4", name, len(eps))
+		}
+		srv := eps["198.51.100.80"]
+		if srv == nil || srv.Packets != 2 || srv.Bytes != 108 || srv.Ports["443/tcp"] != 2 {
+			t.Errorf("%s: unexpected server endpoint %+v", name, srv)
+		}
+		if dns := eps["2001:db8::53"]; dns == nil || dns.Packets != 1 || dns.Ports["53/udp"] != 1 {
+			t.Errorf("%s: unexpected IPv6 endpoint %+v", name, dns)
+		}
+		if srv != nil && srv.FirstSeen.Unix() < 1760000000 {
+			t.Errorf("%s: bad timestamp %v", name, srv.FirstSeen)
+		}
+	}
+}
+
+func TestPcapRejectsOtherFiles(t *testing.T) {
+	if _, err := pcap.NewReader(bytes.NewReader([]byte("not a capture at all"))); err == nil {
+		t.Error("NewReader accepted a text file")
+	}
+}